@@ -177,7 +177,7 @@ func (a *App) buildCommand() {
 // Run 执行构建好的程序,会按顺序执行注册到cobra.Command中的运行函数
 func (a *App) Run() {
 	if err := a.cmd.Execute(); err != nil {
-		fmt.Printf("%v %v\n", color.RedString("Error:", err))
+		fmt.Printf("%v %v\n", color.RedString("Error:"), err)
 		os.Exit(1)
 	}
 }
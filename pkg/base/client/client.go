@@ -0,0 +1,155 @@
+// Package client 提供服务间HTTP调用的辅助方法,将对端返回的core.ErrResponse还原为带错误码的错误,
+// 使errors.IsCode等方法能够跨越服务边界使用
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+//读取错误响应体的上限,避免对端返回超大的响应
+const maxErrorBody = 1 << 20
+
+// Error 是4xx,5xx响应转换得到的错误,响应体中带有错误码时,Decode返回的带错误码的错误以其作为cause
+type Error struct {
+	StatusCode int
+	Code       int
+	Message    string
+	Reference  string
+
+	retryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Message)
+}
+
+// RetryAfter 返回响应的Retry-After头,供errors.RetryAfter使用
+func (e *Error) RetryAfter() time.Duration { return e.retryAfter }
+
+//errBody 同时兼容core.ErrResponse和core.ProblemDetails
+type errBody struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Reference string `json:"reference"`
+	Type      string `json:"type"`
+	Detail    string `json:"detail"`
+}
+
+// Decode 检查响应的状态码,小于400时返回nil;否则读取并关闭响应体,将其中的错误码还原为带错误码的错误,
+// 错误码未在本进程注册时ParseCoder返回对端的http状态码和参考文档;
+// 响应体不是合法的错误响应时,返回不携带错误码的*Error
+func Decode(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var body errBody
+	if err := json.Unmarshal(data, &body); err != nil || body.Code == 0 {
+		e.Message = http.StatusText(resp.StatusCode)
+		return e
+	}
+
+	e.Code = body.Code
+	e.Message = body.Message
+	if e.Message == "" {
+		e.Message = body.Detail
+	}
+	e.Reference = body.Reference
+	if e.Reference == "" {
+		e.Reference = body.Type
+	}
+	return errors.Remote(e.Code, e.StatusCode, e.Message, e.Reference, e)
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Client 包装http.Client,所有方法返回的4xx,5xx响应都会被Decode转换为错误,
+// 重定向和304等其他响应原样返回,由调用方关闭响应体
+type Client struct {
+	hc *http.Client
+}
+
+// New 返回一个Client,timeout为0时不设置超时
+func New(timeout time.Duration) *Client {
+	return &Client{hc: &http.Client{Timeout: timeout}}
+}
+
+// NewClient 使用已配置好的http.Client(如自定义Transport)创建Client,hc为nil时使用http.DefaultClient
+func NewClient(hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{hc: hc}
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := Decode(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) Get(url string) (*http.Response, error) {
+	return c.send(http.MethodGet, url, "", nil)
+}
+
+func (c *Client) Head(url string) (*http.Response, error) {
+	return c.send(http.MethodHead, url, "", nil)
+}
+
+func (c *Client) Post(url, contentType string, body io.Reader) (*http.Response, error) {
+	return c.send(http.MethodPost, url, contentType, body)
+}
+
+func (c *Client) PostForm(url string, data url.Values) (*http.Response, error) {
+	return c.Post(url, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+}
+
+// CloseIdleConnections 关闭底层http.Client的空闲连接
+func (c *Client) CloseIdleConnections() {
+	c.hc.CloseIdleConnections()
+}
+
+func (c *Client) send(method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return c.Do(req)
+}
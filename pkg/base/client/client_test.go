@@ -0,0 +1,104 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/base/core"
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+var errUserNotFound = errors.NewCode(150001, http.StatusNotFound, "User not found", "ref-150001")
+
+func newServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/users/1", func(c *gin.Context) {
+		core.WriteResponse(c, errors.WithCode(errUserNotFound, "user 1 not found"), nil)
+	})
+	r.GET("/v2/users/1", core.UseResponseMode(core.ModeProblem), func(c *gin.Context) {
+		core.WriteResponse(c, errors.WithCode(errUserNotFound, "user 1 not found"), nil)
+	})
+	r.GET("/busy", func(c *gin.Context) {
+		c.Header("Retry-After", "3")
+		c.String(http.StatusServiceUnavailable, "try later")
+	})
+	r.GET("/moved", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/ok")
+	})
+	r.GET("/cached", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+	r.POST("/users", func(c *gin.Context) {
+		core.WriteResponse(c, errors.WithCode(errUserNotFound, "user %s not found", c.PostForm("name")), nil)
+	})
+	r.GET("/ok", func(c *gin.Context) {
+		core.WriteResponse(c, nil, gin.H{"name": "foo"})
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newServer(t)
+	c := New(time.Second)
+
+	for _, path := range []string{"/users/1", "/v2/users/1"} {
+		_, err := c.Get(srv.URL + path)
+		if !errors.IsCode(err, 150001) || !errors.Is(err, errUserNotFound) {
+			t.Errorf("%s: want code 150001, got %v", path, err)
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound || e.Reference != "ref-150001" {
+			t.Errorf("%s: unexpected error %#v", path, e)
+		}
+	}
+
+	//Head,PostForm同样经过Decode
+	var e *Error
+	if _, err := c.Head(srv.URL + "/users/1"); !errors.As(err, &e) || e.StatusCode != http.StatusNotFound {
+		t.Errorf("Head: want a 404 error, got %v", err)
+	}
+	if _, err := c.PostForm(srv.URL+"/users", url.Values{"name": {"foo"}}); !errors.IsCode(err, 150001) {
+		t.Errorf("PostForm: want code 150001, got %v", err)
+	}
+
+	_, err := c.Get(srv.URL + "/busy")
+	if _, ok := errors.CodeOf(err); ok {
+		t.Errorf("a non ErrResponse body should not carry a code, got %v", err)
+	}
+	if got := errors.RetryAfter(err); got != 3*time.Second {
+		t.Errorf("RetryAfter: got %v, want 3s", got)
+	}
+
+	for path, want := range map[string]int{"/ok": http.StatusOK, "/moved": http.StatusOK, "/cached": http.StatusNotModified} {
+		resp, err := c.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: got status %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestDecodeUnregistered(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusConflict,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"code":150099,"message":"Order closed","reference":"ref-150099"}`)),
+	}
+	err := Decode(resp)
+	coder := errors.ParseCoder(err)
+	if !errors.IsCode(err, 150099) || coder.HTTPStatus() != http.StatusConflict || coder.Reference() != "ref-150099" {
+		t.Errorf("unexpected coder %v for %v", coder, err)
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+// CatalogEntry 是错误码目录中的一项
+type CatalogEntry struct {
+	Code       int    `json:"code"`
+	HTTPStatus int    `json:"httpStatus"`
+	Message    string `json:"message"`
+	Reference  string `json:"reference,omitempty"`
+	Module     string `json:"module,omitempty"`
+}
+
+var catalogTemplate = template.Must(template.New("catalog").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Error codes</title></head>
+<body>
+<table border="1" cellpadding="4">
+<tr><th>Code</th><th>HTTP Status</th><th>Message</th><th>Reference</th><th>Module</th></tr>
+{{- range .}}
+<tr><td>{{.Code}}</td><td>{{.HTTPStatus}}</td><td>{{.Message}}</td><td>{{.Reference}}</td><td>{{.Module}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// ErrorCatalog 返回一个列出所有已注册错误码的handler,便于前端和合作方查询ErrResponse中的code,example:
+//
+//	router.GET("/errors", core.ErrorCatalog())
+//
+// 支持的查询参数:
+//   - module 只列出指定模块的错误码
+//   - code   只列出指定的错误码
+//   - format 为html时输出HTML页面,也可以通过Accept: text/html指定,默认输出JSON
+//
+// 响应携带ETag,请求的If-None-Match匹配时返回304;响应格式取决于Accept,因此同时携带Vary: Accept
+func ErrorCatalog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entries, err := catalogEntries(c.Query("module"), c.Query("code"))
+		if err != nil {
+			WriteResponse(c, err, nil)
+			return
+		}
+
+		html := c.Query("format") == "html" ||
+			(c.Query("format") == "" && strings.Contains(c.GetHeader("Accept"), "text/html"))
+
+		var (
+			body        bytes.Buffer
+			contentType string
+		)
+		if html {
+			contentType = "text/html; charset=utf-8"
+			err = catalogTemplate.Execute(&body, entries)
+		} else {
+			contentType = "application/json; charset=utf-8"
+			err = json.NewEncoder(&body).Encode(entries)
+		}
+		if err != nil {
+			WriteResponse(c, errors.WrapC(err, errors.UnknownCode, "render error catalog"), nil)
+			return
+		}
+
+		sum := sha256.Sum256(body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Header("Vary", "Accept")
+		c.Header("ETag", etag)
+		if match := c.GetHeader("If-None-Match"); match != "" && strings.Contains(match, etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Data(http.StatusOK, contentType, body.Bytes())
+	}
+}
+
+func catalogEntries(module, code string) ([]CatalogEntry, error) {
+	var coders []errors.Coder
+	if module != "" {
+		coders = errors.CodesByModule(module)
+	} else {
+		coders = errors.ListCoders()
+	}
+
+	want := 0
+	if code != "" {
+		var err error
+		if want, err = strconv.Atoi(code); err != nil {
+			return nil, errors.WithCode(errors.BadRequestCode, "invalid code %q", code)
+		}
+	}
+
+	entries := make([]CatalogEntry, 0, len(coders))
+	for _, coder := range coders {
+		if want != 0 && coder.Code() != want {
+			continue
+		}
+		entry := CatalogEntry{
+			Code:       coder.Code(),
+			HTTPStatus: coder.HTTPStatus(),
+			Message:    coder.String(),
+			Reference:  coder.Reference(),
+		}
+		if m, ok := errors.ModuleOf(coder.Code()); ok {
+			entry.Module = m.Name()
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -3,9 +3,11 @@ package core
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/leilei3167/basic/pkg/errors"
+	logger "github.com/leilei3167/basic/pkg/log"
 )
 
 //提供通用的 响应结构
@@ -16,12 +18,58 @@ type ErrResponse struct {
 	Reference string `json:"reference,omitempty"`
 }
 
+// ProblemDetails 是RFC 7807定义的错误响应结构,业务错误码和requestID作为扩展字段
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      int    `json:"code"`
+	RequestID string `json:"requestID,omitempty"`
+}
+
+// ResponseMode 决定WriteResponse输出错误时使用的格式
+type ResponseMode int
+
+const (
+	// ModeDefault 输出ErrResponse
+	ModeDefault ResponseMode = iota
+	// ModeProblem 输出application/problem+json
+	ModeProblem
+	// ModeNegotiate 根据请求的Accept头决定,包含application/problem+json时输出ProblemDetails
+	ModeNegotiate
+)
+
+const (
+	ContentTypeProblem = "application/problem+json"
+
+	responseModeKey = "core.response-mode"
+	headerRequestID = "X-Request-ID"
+)
+
+// UseResponseMode 返回一个中间件,为路由组设置错误响应的格式,example:
+//
+//	v2 := router.Group("/v2", core.UseResponseMode(core.ModeProblem))
+func UseResponseMode(mode ResponseMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(responseModeKey, mode)
+		c.Next()
+	}
+}
+
 func WriteResponse(c *gin.Context, err error, data any) {
 	if err != nil {
 		//日志记录可以记录详细信息(调用堆栈)
 		log.Printf("%#+v", err)
 		//错误必须提前注册到errors中,返回至前端的是脱敏的信息
 		coder := errors.ParseCoder(err)
+		runHooks(c, coder.Code(), coder.HTTPStatus())
+		if useProblem(c) {
+			c.Header("Content-Type", ContentTypeProblem)
+			c.JSON(coder.HTTPStatus(), newProblemDetails(c, coder))
+			return
+		}
 		c.JSON(coder.HTTPStatus(), ErrResponse{
 			Code:      coder.Code(),
 			Message:   coder.String(),
@@ -31,3 +79,34 @@ func WriteResponse(c *gin.Context, err error, data any) {
 	}
 	c.JSON(http.StatusOK, data)
 }
+
+func useProblem(c *gin.Context) bool {
+	mode, _ := c.Get(responseModeKey)
+	switch mode {
+	case ModeProblem:
+		return true
+	case ModeNegotiate:
+		return strings.Contains(c.GetHeader("Accept"), ContentTypeProblem)
+	}
+	return false
+}
+
+func newProblemDetails(c *gin.Context, coder errors.Coder) ProblemDetails {
+	typ := coder.Reference()
+	if typ == "" || typ == "none" { //没有参考文档时,按照RFC 7807使用about:blank
+		typ = "about:blank"
+	}
+	requestID := c.GetString(logger.KeyRequestID)
+	if requestID == "" {
+		requestID = c.GetHeader(headerRequestID)
+	}
+	return ProblemDetails{
+		Type:      typ,
+		Title:     http.StatusText(coder.HTTPStatus()),
+		Status:    coder.HTTPStatus(),
+		Detail:    coder.String(),
+		Instance:  c.Request.URL.Path,
+		Code:      coder.Code(),
+		RequestID: requestID,
+	}
+}
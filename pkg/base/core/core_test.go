@@ -0,0 +1,163 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+const codeUserNotFound = 130001
+
+type testCoder struct{}
+
+func (testCoder) HTTPStatus() int   { return http.StatusNotFound }
+func (testCoder) String() string    { return "User not found" }
+func (testCoder) Reference() string { return "https://example.com/errors/130001" }
+func (testCoder) Code() int         { return codeUserNotFound }
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	errors.Register(testCoder{})
+}
+
+func newRouter() *gin.Engine {
+	r := gin.New()
+	handler := func(c *gin.Context) {
+		WriteResponse(c, errors.WithCode(codeUserNotFound, "user %d not found", 1), nil)
+	}
+	r.GET("/v1/users/1", handler)
+	r.GET("/v2/users/1", UseResponseMode(ModeProblem), handler)
+	r.GET("/v3/users/1", UseResponseMode(ModeNegotiate), handler)
+	return r
+}
+
+func TestWriteResponseModes(t *testing.T) {
+	tests := []struct {
+		path        string
+		accept      string
+		contentType string
+	}{
+		{"/v1/users/1", ContentTypeProblem, "application/json; charset=utf-8"},
+		{"/v2/users/1", "", ContentTypeProblem},
+		{"/v3/users/1", "application/json", "application/json; charset=utf-8"},
+		{"/v3/users/1", ContentTypeProblem + ", application/json", ContentTypeProblem},
+	}
+	r := newRouter()
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			req.Header.Set("X-Request-ID", "req-1")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status: got %d, want %d", w.Code, http.StatusNotFound)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("content type: got %q, want %q", got, tt.contentType)
+			}
+			if tt.contentType != ContentTypeProblem {
+				return
+			}
+
+			var p ProblemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+			want := ProblemDetails{
+				Type:      "https://example.com/errors/130001",
+				Title:     "Not Found",
+				Status:    http.StatusNotFound,
+				Detail:    "User not found",
+				Instance:  tt.path,
+				Code:      codeUserNotFound,
+				RequestID: "req-1",
+			}
+			if p != want {
+				t.Errorf("got %+v, want %+v", p, want)
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	r := gin.New()
+	r.Use(Recovery())
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status: got %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	var resp ErrResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != errors.PanicCode {
+		t.Errorf("want code %d, got %+v (%v)", errors.PanicCode, resp, err)
+	}
+
+	r.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("want http.ErrAbortHandler re-panicked, got %v", v)
+		}
+	}()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	t.Error("ErrAbortHandler should not be recovered")
+}
+
+//模块只能声明一次,在包级别声明以便测试可以重复执行
+var (
+	catalogModule  = errors.NewModule("catalog", 130100, 130199)
+	errOrderClosed = catalogModule.NewCode(130101, http.StatusConflict, "Order closed", "")
+)
+
+func TestErrorCatalog(t *testing.T) {
+	r := gin.New()
+	r.GET("/errors", ErrorCatalog())
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/errors?module=catalog", nil)
+	var entries []CatalogEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := CatalogEntry{Code: 130101, HTTPStatus: http.StatusConflict, Message: "Order closed", Module: "catalog"}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("got %+v, want [%+v]", entries, want)
+	}
+
+	w = get("/errors?code=130001", http.Header{"Accept": {"text/html"}})
+	if !strings.Contains(w.Body.String(), "<td>User not found</td>") || strings.Contains(w.Body.String(), "Order closed") {
+		t.Errorf("unexpected html:\n%s", w.Body.String())
+	}
+
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Vary: got %q, want Accept", vary)
+	}
+	etag := w.Header().Get("ETag")
+	w = get("/errors?code=130001", http.Header{"Accept": {"text/html"}, "If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: got %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	w = get("/errors?code=abc", nil)
+	var resp ErrResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest ||
+		resp.Code != errors.BadRequestCode {
+		t.Errorf("invalid code: got %d %+v, want %d with code %d", w.Code, resp, http.StatusBadRequest, errors.BadRequestCode)
+	}
+}
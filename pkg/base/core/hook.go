@@ -0,0 +1,37 @@
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseHook 在WriteResponse输出错误响应时调用,code和httpStatus与返回给前端的一致
+type ResponseHook func(c *gin.Context, code, httpStatus int)
+
+var (
+	hookMux sync.Mutex
+	hooks   atomic.Pointer[[]ResponseHook]
+)
+
+// AddResponseHook 添加一个ResponseHook,必须是并发安全的
+func AddResponseHook(hook ResponseHook) {
+	hookMux.Lock()
+	defer hookMux.Unlock()
+
+	var hs []ResponseHook
+	if prev := hooks.Load(); prev != nil {
+		hs = append(hs, *prev...)
+	}
+	hs = append(hs, hook)
+	hooks.Store(&hs)
+}
+
+func runHooks(c *gin.Context, code, httpStatus int) {
+	if hs := hooks.Load(); hs != nil {
+		for _, h := range *hs {
+			h(c, code, httpStatus)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/errors"
+	logger "github.com/leilei3167/basic/pkg/log"
+)
+
+// Recovery 返回一个中间件,将处理请求时的panic转换为带错误码(errors.PanicCode)的错误,
+// 记录日志后以WriteResponse的格式返回,用于替代gin.Recovery,
+// http.ErrAbortHandler会被重新panic,交给net/http中断连接
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var err error
+		defer func() {
+			if err == nil {
+				return
+			}
+			if errors.Is(err, http.ErrAbortHandler) {
+				panic(http.ErrAbortHandler)
+			}
+			logger.L(c).Error("panic recovered", logger.String("path", c.Request.URL.Path), logger.ErrorField(err))
+			WriteResponse(c, err, nil)
+			c.Abort()
+		}()
+		defer errors.Recover(&err)
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/base/core"
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+//otherCode 未在本进程注册的错误码统一使用的code标签,避免标签的取值无限增长
+const otherCode = "other"
+
+// ErrorMetrics 统计错误的创建和错误响应,标签为code和status
+type ErrorMetrics struct {
+	// Created 统计errors.WithCode,errors.WrapC以及errors.Recover创建的错误,
+	// 不包括errors.Remote从对端还原的错误
+	Created *CounterVec
+	// Responses 统计core.WriteResponse输出的错误响应
+	Responses *CounterVec
+}
+
+var (
+	installMux sync.Mutex
+	installed  = map[*Registry]*ErrorMetrics{}
+)
+
+// InstallErrorMetrics 创建错误计数器注册到r,并通过errors.AddCodeHook和core.AddResponseHook接入,
+// 对同一个Registry重复调用时返回第一次创建的ErrorMetrics,example:
+//
+//	metrics.InstallErrorMetrics(metrics.Default)
+//	router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+func InstallErrorMetrics(r *Registry) *ErrorMetrics {
+	installMux.Lock()
+	defer installMux.Unlock()
+	if m, ok := installed[r]; ok {
+		return m
+	}
+
+	m := &ErrorMetrics{
+		Created:   NewCounterVec("errors_created_total", "Number of coded errors created.", "code", "status"),
+		Responses: NewCounterVec("http_error_responses_total", "Number of error responses written.", "code", "status"),
+	}
+	r.MustRegister(m.Created, m.Responses)
+
+	errors.AddCodeHook(func(code, httpStatus int) {
+		m.Created.Inc(codeLabel(code), strconv.Itoa(httpStatus))
+	})
+	core.AddResponseHook(func(_ *gin.Context, code, httpStatus int) {
+		m.Responses.Inc(codeLabel(code), strconv.Itoa(httpStatus))
+	})
+	installed[r] = m
+	return m
+}
+
+func codeLabel(code int) string {
+	if _, ok := errors.Lookup(code); !ok {
+		return otherCode
+	}
+	return strconv.Itoa(code)
+}
@@ -0,0 +1,164 @@
+// Package metrics 提供只依赖标准库的计数器,以及Prometheus文本格式的暴露接口
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CounterVec 是一组按标签区分的计数器
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counter
+}
+
+type counter struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec 创建一个计数器,labels为标签名称
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counter)}
+}
+
+// Inc 将指定标签值的计数加1,labelValues的数量必须与标签名称一致
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 将指定标签值的计数增加v,v不能为负数
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", c.name, len(c.labels), len(labelValues)))
+	}
+	if v < 0 {
+		panic(fmt.Sprintf("metric %s: counter cannot decrease", c.name))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ct, ok := c.values[key]
+	if !ok {
+		ct = &counter{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = ct
+	}
+	ct.value += v
+}
+
+// Value 返回指定标签值的当前计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ct, ok := c.values[strings.Join(labelValues, "\xff")]; ok {
+		return ct.value
+	}
+	return 0
+}
+
+//writeTo 按Prometheus文本格式输出,样本按标签值排序以保证输出稳定
+func (c *CounterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	samples := make([]counter, 0, len(c.values))
+	for _, ct := range c.values {
+		samples = append(samples, *ct)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].labelValues, samples[j].labelValues
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+
+	if c.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", c.name, escapeHelp(c.help))
+	}
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	for _, s := range samples {
+		io.WriteString(w, c.name)
+		if len(c.labels) > 0 {
+			io.WriteString(w, "{")
+			for i, l := range c.labels {
+				if i > 0 {
+					io.WriteString(w, ",")
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabel(s.labelValues[i]))
+			}
+			io.WriteString(w, "}")
+		}
+		fmt.Fprintf(w, " %s\n", strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpReplacer.Replace(s) }
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
+
+// Registry 管理一组计数器并通过Handler暴露
+type Registry struct {
+	mu       sync.Mutex
+	counters []*CounterVec
+}
+
+// NewRegistry 创建一个空的Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default 是默认的Registry
+var Default = NewRegistry()
+
+// MustRegister 注册计数器,名称重复时panic
+func (r *Registry) MustRegister(cs ...*CounterVec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range cs {
+		for _, exist := range r.counters {
+			if exist.name == c.name {
+				panic(fmt.Sprintf("metric %s is already registered", c.name))
+			}
+		}
+		r.counters = append(r.counters, c)
+	}
+}
+
+// WriteText 按Prometheus文本格式输出所有计数器,按名称排序
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	cs := append([]*CounterVec(nil), r.counters...)
+	r.mu.Unlock()
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].name < cs[j].name })
+	for _, c := range cs {
+		c.writeTo(w)
+	}
+}
+
+// Handler 返回以Prometheus文本格式暴露指标的http.Handler,example:
+//
+//	http.Handle("/metrics", metrics.Default.Handler())
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
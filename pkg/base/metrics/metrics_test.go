@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leilei3167/basic/pkg/base/core"
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+var errOrderClosed = errors.NewCode(160001, http.StatusConflict, "Order closed", "")
+
+func TestErrorMetrics(t *testing.T) {
+	reg := NewRegistry()
+	if InstallErrorMetrics(reg) != InstallErrorMetrics(reg) {
+		t.Fatal("installing twice should return the same metrics")
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/orders/1", func(c *gin.Context) {
+		core.WriteResponse(c, errors.WrapC(errors.New("closed"), errOrderClosed, "order 1"), nil)
+	})
+	r.GET("/metrics", gin.WrapH(reg.Handler()))
+
+	_ = errors.WithCode(160001, "order %d", 2)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	_ = errors.Wrap(errors.WithCode(999999, `a "quoted" code`), "not counted")
+	_ = errors.WithCode(999998, "another unregistered code")
+	_ = errors.Remote(160001, http.StatusConflict, "Order closed", "", nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `# HELP errors_created_total Number of coded errors created.
+# TYPE errors_created_total counter
+errors_created_total{code="160001",status="409"} 2
+errors_created_total{code="other",status="500"} 2
+# HELP http_error_responses_total Number of error responses written.
+# TYPE http_error_responses_total counter
+http_error_responses_total{code="160001",status="409"} 1
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	c := NewCounterVec("x_total", "", "l")
+	c.Inc("a\"b\\c\nd")
+	reg := NewRegistry()
+	reg.MustRegister(c)
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, nil)
+	want := "# TYPE x_total counter\nx_total{l=\"a\\\"b\\\\c\\nd\"} 1\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
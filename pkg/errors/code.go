@@ -3,12 +3,25 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 	"sync"
 )
 
+//预留的内部错误码,不属于任何模块
+const (
+	// UnknownCode 未注册的错误码以及不带错误码的错误使用的错误码
+	UnknownCode = 1
+	// BadRequestCode 请求参数不合法且没有更具体的业务错误码时使用的错误码
+	BadRequestCode = 3
+)
+
 var (
-	unknownCoder = defaultCoder{C: 1, HTTP: http.StatusInternalServerError,
+	unknownCoder = defaultCoder{C: UnknownCode, HTTP: http.StatusInternalServerError,
 		Ext: "An internal server error occurred", Ref: "none"}
+	panicCoder = defaultCoder{C: PanicCode, HTTP: http.StatusInternalServerError,
+		Ext: "Internal server error", Ref: "none"}
+	badRequestCoder = defaultCoder{C: BadRequestCode, HTTP: http.StatusBadRequest,
+		Ext: "Bad request", Ref: "none"}
 
 	codes   = map[int]Coder{}
 	codeMux = &sync.Mutex{}
@@ -16,6 +29,8 @@ var (
 
 func init() {
 	codes[unknownCoder.Code()] = unknownCoder
+	codes[panicCoder.Code()] = panicCoder
+	codes[badRequestCoder.Code()] = badRequestCoder
 }
 
 //Coder 接口定义了一个错误码的详细信息接口
@@ -52,7 +67,7 @@ func (coder defaultCoder) Reference() string {
 	return coder.Ref
 }
 
-//将错误和对应的错误信息注册
+//将错误和对应的错误信息注册,开启StrictModules后错误码必须在某个模块的范围内
 
 func Register(coder Coder) {
 	if coder.Code() == 0 {
@@ -60,6 +75,9 @@ func Register(coder Coder) {
 	}
 	codeMux.Lock()
 	defer codeMux.Unlock()
+	if err := checkRange(coder.Code()); err != nil {
+		panic(err.Error())
+	}
 	codes[coder.Code()] = coder
 }
 
@@ -70,9 +88,12 @@ func MustRegister(coder Coder) {
 	}
 	codeMux.Lock()
 	defer codeMux.Unlock()
+	if err := checkRange(coder.Code()); err != nil {
+		panic(err.Error())
+	}
 
 	if _, ok := codes[coder.Code()]; ok {
-		panic(fmt.Sprintf("code: %d is already exist", coder.Code()))
+		panic(fmt.Sprintf("code %d is already registered", coder.Code()))
 	}
 
 	codes[coder.Code()] = coder
@@ -82,17 +103,37 @@ func ParseCoder(err error) Coder {
 	if err == nil {
 		return nil
 	}
+	if c, ok := err.(*Code); ok {
+		return c
+	}
 
 	if v, ok := err.(*withCode); ok { //必须是*withCode类型
-		if coder, ok := codes[v.code]; ok {
+		if coder, ok := v.coder(); ok {
 			return coder
 		}
 	}
 	return unknownCoder
 }
 
+//coder 返回错误码对应的Coder,优先使用本进程注册的,其次是Remote携带的
+func (w *withCode) coder() (Coder, bool) {
+	codeMux.Lock()
+	coder, ok := codes[w.code]
+	codeMux.Unlock()
+	if ok {
+		return coder, true
+	}
+	if w.remote != nil {
+		return w.remote, true
+	}
+	return nil, false
+}
+
 //IsCode 判断某个错误及其错误链上是否有对应错误码的错误
 func IsCode(err error, code int) bool {
+	if c, ok := err.(*Code); ok {
+		return c.code == code
+	}
 	if v, ok := err.(*withCode); ok {
 		if v.code == code {
 			return true
@@ -105,3 +146,71 @@ func IsCode(err error, code int) bool {
 	}
 	return false
 }
+
+// CodeOf 返回错误链最外层的业务错误码,与ParseCoder不同,即使该错误码未在本进程注册也会原样返回
+func CodeOf(err error) (int, bool) {
+	switch v := err.(type) {
+	case *withCode:
+		return v.code, true
+	case *Code:
+		return v.code, true
+	}
+	return 0, false
+}
+
+// Lookup 返回本进程注册的错误码对应的Coder
+func Lookup(code int) (Coder, bool) {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+	coder, ok := codes[code]
+	return coder, ok
+}
+
+// Code 是一个已注册的错误码,同时实现了Coder和error接口,可以作为可比较的哨兵值使用,
+// 注册,查询和匹配共用同一个值,example:
+//
+//	var ErrUserNotFound = errors.NewCode(100101, http.StatusNotFound, "User not found", "")
+//
+//	err := errors.WithCode(ErrUserNotFound, "user %q not found", name)
+//	errors.Is(err, ErrUserNotFound) // true
+type Code struct {
+	code int
+	http int
+	ext  string
+	ref  string
+}
+
+// NewCode 创建并注册一个错误码,错误码重复时panic
+func NewCode(code, httpStatus int, message, reference string) *Code {
+	c := &Code{code: code, http: httpStatus, ext: message, ref: reference}
+	MustRegister(c)
+	return c
+}
+
+func (c *Code) Code() int         { return c.code }
+func (c *Code) String() string    { return c.ext }
+func (c *Code) Reference() string { return c.ref }
+func (c *Code) Error() string     { return c.ext }
+
+func (c *Code) HTTPStatus() int {
+	if c.http == 0 {
+		return http.StatusInternalServerError
+	}
+	return c.http
+}
+
+// CodeValue 是WithCode和WrapC接受的错误码类型:整型错误码(包括以int为底层类型的自定义类型)
+// 或者NewCode创建的哨兵
+type CodeValue interface {
+	~int | *Code
+}
+
+func codeValue[C CodeValue](code C) int {
+	if c, ok := any(code).(*Code); ok {
+		if c == nil {
+			return unknownCoder.Code()
+		}
+		return c.code
+	}
+	return int(reflect.ValueOf(code).Int())
+}
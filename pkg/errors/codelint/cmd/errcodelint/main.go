@@ -0,0 +1,13 @@
+// errcodelint 以go vet工具的形式运行codelint:
+//
+//	go vet -vettool=$(which errcodelint) -errcode.ranges=example.com/app/user=100100-100199 ./...
+package main
+
+import (
+	"github.com/leilei3167/basic/pkg/errors/codelint"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(codelint.Analyzer)
+}
@@ -0,0 +1,462 @@
+// Package codelint 提供一个静态检查错误码使用的analysis.Analyzer,可以通过go vet运行:
+//
+//	go install github.com/leilei3167/basic/pkg/errors/codelint/cmd/errcodelint
+//	go vet -vettool=$(which errcodelint) ./...
+//
+// 它会检查:
+//   - 同一个错误码在多个位置(包括跨包)被注册
+//   - WithCode/WrapC 使用了从未注册过的错误码,只检查属于本包或依赖中模块的错误码,
+//     模块来自errors.NewModule的声明以及-ranges,其他错误码可能在没有被导入的包中注册,无法判断
+//   - 注册的错误码超出了所在模块配置的范围(-ranges)
+package codelint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const errorsPkg = "github.com/leilei3167/basic/pkg/errors"
+
+// Analyzer 检查错误码的注册与使用
+var Analyzer = &analysis.Analyzer{
+	Name:      "errcode",
+	Doc:       "check that business error codes are unique, registered before use and inside their module's range",
+	Run:       run,
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	FactTypes: []analysis.Fact{new(codesFact)},
+}
+
+var (
+	//用于注册错误码的函数,错误码为名为code的整型参数,或者名为coder的参数中的错误码
+	registerFuncs = stringList{
+		errorsPkg + ".Register", errorsPkg + ".MustRegister", errorsPkg + ".NewCode",
+		"(*" + errorsPkg + ".Module).Register", "(*" + errorsPkg + ".Module).MustRegister",
+		"(*" + errorsPkg + ".Module).NewCode",
+	}
+	//模块的错误码范围,包路径前缀=最小值-最大值
+	ranges = rangeList{}
+)
+
+func init() {
+	Analyzer.Flags.Var(&registerFuncs, "register",
+		"comma-separated list of additional functions (pkgpath.Name) that register error codes, "+
+			"the code is taken from the int parameter named code or the parameter named coder")
+	Analyzer.Flags.Var(&ranges, "ranges",
+		"comma-separated list of module code ranges, e.g. example.com/app/user=100100-100199")
+}
+
+//codeArgs 使用错误码的函数及错误码参数所在的位置
+var codeArgs = map[string]int{
+	errorsPkg + ".WithCode":              0,
+	errorsPkg + ".WrapC":                 1,
+	errorsPkg + ".WithCodeDepth":         1,
+	errorsPkg + ".WrapCDepth":            2,
+	"(" + errorsPkg + ".Depth).WithCode": 0,
+	"(" + errorsPkg + ".Depth).WrapC":    1,
+}
+
+// codesFact 记录一个包及其所有依赖中注册过的错误码,值为注册的位置,
+// 以及通过errors.NewModule声明的模块范围
+type codesFact struct {
+	Codes   map[int]string
+	Modules []moduleRange
+}
+
+// moduleRange 是errors.NewModule声明的模块,字段需要导出以便facts编码
+type moduleRange struct {
+	Name     string
+	Min, Max int
+}
+
+func (m moduleRange) String() string { return fmt.Sprintf("%s=%d-%d", m.Name, m.Min, m.Max) }
+
+func (*codesFact) AFact() {}
+
+func (f *codesFact) String() string {
+	keys := make([]int, 0, len(f.Codes))
+	for k := range f.Codes {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	if len(f.Modules) == 0 {
+		return fmt.Sprintf("codes%v", keys)
+	}
+	return fmt.Sprintf("codes%v modules%v", keys, f.Modules)
+}
+
+type registration struct {
+	code int
+	pos  token.Pos
+}
+
+type usage struct {
+	code int
+	pos  token.Pos
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	var (
+		regs []registration
+		uses []usage
+		mods []moduleRange
+	)
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name := calleeName(pass, call)
+		if name == "" {
+			return
+		}
+		if registerFuncs.contains(name) {
+			if code, ok := paramCode(pass, call); ok {
+				regs = append(regs, registration{code: code, pos: call.Pos()})
+			}
+			return
+		}
+		if name == errorsPkg+".NewModule" {
+			if m, ok := newModuleRange(pass, call); ok {
+				mods = append(mods, m)
+			}
+			return
+		}
+		if i, ok := codeArgs[name]; ok && i < len(call.Args) {
+			if code, ok := intConst(pass, call.Args[i]); ok {
+				uses = append(uses, usage{code: code, pos: call.Args[i].Pos()})
+			}
+		}
+	})
+
+	known, modules := importedCodes(pass)
+	modules = append(modules, mods...)
+
+	//本包的注册,检查重复和范围
+	local := map[int]string{}
+	for _, r := range regs {
+		where := pass.Fset.Position(r.pos).String()
+		if prev, ok := local[r.code]; ok {
+			pass.Reportf(r.pos, "duplicate error code %d, already registered at %s", r.code, prev)
+			continue
+		}
+		if prev, ok := known[r.code]; ok {
+			pass.Reportf(r.pos, "duplicate error code %d, already registered at %s", r.code, prev)
+			continue
+		}
+		if rg, ok := ranges.lookup(pass.Pkg.Path()); ok && (r.code < rg.min || r.code > rg.max) {
+			pass.Reportf(r.pos, "error code %d is outside the range %d-%d of module %s",
+				r.code, rg.min, rg.max, rg.prefix)
+		}
+		local[r.code] = where
+	}
+
+	for _, u := range uses {
+		if _, ok := local[u.code]; ok {
+			continue
+		}
+		if _, ok := known[u.code]; ok {
+			continue
+		}
+		if owned(pass, modules, u.code) {
+			pass.Reportf(u.pos, "error code %d is never registered", u.code)
+		}
+	}
+
+	for code, where := range local {
+		known[code] = where
+	}
+	if len(known) > 0 || len(modules) > 0 {
+		pass.ExportPackageFact(&codesFact{Codes: known, Modules: modules})
+	}
+	return nil, nil
+}
+
+//importedCodes 合并所有直接依赖导出的错误码和模块,依赖之间的冲突报告在对应的import语句上
+func importedCodes(pass *analysis.Pass) (map[int]string, []moduleRange) {
+	known := map[int]string{}
+	owner := map[int]string{}
+	var modules []moduleRange
+	seen := map[moduleRange]bool{}
+	for _, imp := range pass.Pkg.Imports() {
+		var fact codesFact
+		if !pass.ImportPackageFact(imp, &fact) {
+			continue
+		}
+		for _, m := range fact.Modules {
+			if !seen[m] {
+				seen[m] = true
+				modules = append(modules, m)
+			}
+		}
+		for code, where := range fact.Codes {
+			if prev, ok := known[code]; ok && prev != where {
+				pass.Reportf(importPos(pass, imp.Path()),
+					"error code %d is registered at both %s (via %s) and %s (via %s)",
+					code, prev, owner[code], where, imp.Path())
+				continue
+			}
+			known[code] = where
+			owner[code] = imp.Path()
+		}
+	}
+	return known, modules
+}
+
+//newModuleRange 从errors.NewModule(name, min, max)的调用中提取模块范围,参数必须都是常量
+func newModuleRange(pass *analysis.Pass, call *ast.CallExpr) (moduleRange, bool) {
+	if len(call.Args) != 3 {
+		return moduleRange{}, false
+	}
+	tv, ok := pass.TypesInfo.Types[call.Args[0]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return moduleRange{}, false
+	}
+	min, ok := intConst(pass, call.Args[1])
+	if !ok {
+		return moduleRange{}, false
+	}
+	max, ok := intConst(pass, call.Args[2])
+	if !ok || min > max {
+		return moduleRange{}, false
+	}
+	return moduleRange{Name: constant.StringVal(tv.Value), Min: min, Max: max}, true
+}
+
+func importPos(pass *analysis.Pass, path string) token.Pos {
+	for _, f := range pass.Files {
+		for _, spec := range f.Imports {
+			if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == path {
+				return spec.Pos()
+			}
+		}
+	}
+	return pass.Files[0].Package
+}
+
+//calleeName 返回被调用函数的全名,如 github.com/leilei3167/basic/pkg/errors.WithCode
+func calleeName(pass *analysis.Pass, call *ast.CallExpr) string {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+	fn = fn.Origin() //泛型函数取其原始声明
+	return fn.FullName() //方法的形式为 pkgpath.(*Recv).Name
+}
+
+//paramCode 从函数调用中提取错误码:名为code的整型常量参数,或者名为coder的参数中的错误码
+func paramCode(pass *analysis.Pass, call *ast.CallExpr) (int, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok {
+		return 0, false
+	}
+	sig := fn.Type().(*types.Signature)
+	for i := 0; i < sig.Params().Len() && i < len(call.Args); i++ {
+		switch sig.Params().At(i).Name() {
+		case "code":
+			return intConst(pass, call.Args[i])
+		case "coder":
+			return coderCode(pass, call.Args[i])
+		}
+	}
+	return 0, false
+}
+
+//coderCode 从Coder表达式中提取错误码:Coder的字面量(字段C或Code,或Code方法返回常量),
+//整型常量的类型转换(如coder(100101)),或者errors包中构造Coder的函数(如errors.WithRetry)
+func coderCode(pass *analysis.Pass, expr ast.Expr) (int, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return coderCode(pass, e.X)
+		}
+	case *ast.CompositeLit:
+		if code, ok := methodCode(pass, e); ok {
+			return code, true
+		}
+		for _, elt := range e.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			if id, ok := kv.Key.(*ast.Ident); ok && (id.Name == "C" || id.Name == "Code") {
+				return intConst(pass, kv.Value)
+			}
+		}
+	case *ast.CallExpr:
+		if tv, ok := pass.TypesInfo.Types[e.Fun]; ok && tv.IsType() {
+			if len(e.Args) == 1 {
+				return intConst(pass, e.Args[0])
+			}
+			return 0, false
+		}
+		if fn, ok := typeutil.Callee(pass.TypesInfo, e).(*types.Func); ok && fn.Pkg() != nil && fn.Pkg().Path() == errorsPkg {
+			return paramCode(pass, e)
+		}
+	}
+	return 0, false
+}
+
+//owned 判断错误码是否属于本包或者依赖中的模块,这些错误码的注册一定能被检查到
+func owned(pass *analysis.Pass, modules []moduleRange, code int) bool {
+	for _, m := range modules {
+		if code >= m.Min && code <= m.Max {
+			return true
+		}
+	}
+	seen := map[*types.Package]bool{}
+	var walk func(pkg *types.Package) bool
+	walk = func(pkg *types.Package) bool {
+		if seen[pkg] {
+			return false
+		}
+		seen[pkg] = true
+		if rg, ok := ranges.lookup(pkg.Path()); ok && code >= rg.min && code <= rg.max {
+			return true
+		}
+		for _, imp := range pkg.Imports() {
+			if walk(imp) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(pass.Pkg)
+}
+
+//methodCode 处理Code方法直接返回常量的Coder,如 func (c notFound) Code() int { return 100101 },
+//只能识别本包中声明的方法
+func methodCode(pass *analysis.Pass, lit *ast.CompositeLit) (int, bool) {
+	tv, ok := pass.TypesInfo.Types[lit]
+	if !ok {
+		return 0, false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(tv.Type, true, pass.Pkg, "Code")
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() != pass.Pkg {
+		return 0, false
+	}
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil || pass.TypesInfo.Defs[fd.Name] != fn {
+				continue
+			}
+			if len(fd.Body.List) != 1 {
+				return 0, false
+			}
+			ret, ok := fd.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				return 0, false
+			}
+			return intConst(pass, ret.Results[0])
+		}
+	}
+	return 0, false
+}
+
+func intConst(pass *analysis.Pass, expr ast.Expr) (int, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	v, exact := constant.Int64Val(tv.Value)
+	if !exact {
+		return 0, false
+	}
+	return int(v), true
+}
+
+//stringList 实现flag.Value,以逗号分隔追加
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+func (l stringList) contains(s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type codeRange struct {
+	prefix   string
+	min, max int
+}
+
+//rangeList 实现flag.Value,格式为 prefix=min-max,以逗号分隔
+type rangeList []codeRange
+
+func (l *rangeList) String() string {
+	parts := make([]string, 0, len(*l))
+	for _, r := range *l {
+		parts = append(parts, fmt.Sprintf("%s=%d-%d", r.prefix, r.min, r.max))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *rangeList) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		prefix, bounds, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("invalid range %q, want prefix=min-max", v)
+		}
+		lo, hi, ok := strings.Cut(bounds, "-")
+		if !ok {
+			return fmt.Errorf("invalid range %q, want prefix=min-max", v)
+		}
+		min, err := strconv.Atoi(lo)
+		if err != nil {
+			return fmt.Errorf("invalid range %q: %v", v, err)
+		}
+		max, err := strconv.Atoi(hi)
+		if err != nil {
+			return fmt.Errorf("invalid range %q: %v", v, err)
+		}
+		if min > max {
+			return fmt.Errorf("invalid range %q: min is greater than max", v)
+		}
+		*l = append(*l, codeRange{prefix: prefix, min: min, max: max})
+	}
+	return nil
+}
+
+//lookup 返回与包路径最长前缀匹配的范围
+func (l rangeList) lookup(pkgPath string) (codeRange, bool) {
+	var (
+		best  codeRange
+		found bool
+	)
+	for _, r := range l {
+		if pkgPath != r.prefix && !strings.HasPrefix(pkgPath, r.prefix+"/") {
+			continue
+		}
+		if !found || len(r.prefix) > len(best.prefix) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,20 @@
+package codelint
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	defer func(old rangeList) { ranges = old }(ranges)
+	if err := Analyzer.Flags.Set("ranges", "a=100100-100199,e=400000-400099"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "a", "b", "c", "d", "e")
+}
+
+func TestAnalyzerModules(t *testing.T) {
+	//不指定-ranges时,模块范围来自errors.NewModule的声明
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "f", "g")
+}
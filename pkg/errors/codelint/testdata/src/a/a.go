@@ -0,0 +1,38 @@
+package a // want package:`codes\[100101 100102 100103 200001\]`
+
+import "github.com/leilei3167/basic/pkg/errors"
+
+type coder struct {
+	C    int
+	HTTP int
+}
+
+func (c coder) Code() int { return c.C }
+
+const (
+	ErrUserNotFound = 100101
+	ErrUserExists   = 100102
+)
+
+func init() {
+	errors.MustRegister(coder{C: ErrUserNotFound, HTTP: 404})
+	errors.MustRegister(&coder{C: ErrUserExists, HTTP: 409})
+	errors.Register(coder{C: ErrUserExists}) // want `duplicate error code 100102, already registered at .*a.go:19:\d+`
+	errors.Register(coder{C: 200001})        // want `error code 200001 is outside the range 100100-100199 of module a`
+}
+
+func find() error {
+	if err := errors.WithCode(ErrUserNotFound, "user %s", "foo"); err != nil {
+		return errors.WrapC(err, 100199, "lookup") // want `error code 100199 is never registered`
+	}
+	return nil
+}
+
+var errSentinel = errors.NewCode(100103, 500, "Internal", "")
+
+func depth() error {
+	_ = errors.WithCode(errSentinel, "sentinel")
+	_ = errors.Depth(1).WithCode(ErrUserExists, "exists")
+	_ = errors.WithCodeDepth(errors.Depth(0), 100198, "depth") // want `error code 100198 is never registered`
+	return errors.Depth(1).WrapC(nil, 100197, "depth")         // want `error code 100197 is never registered`
+}
@@ -0,0 +1,20 @@
+package b // want package:`codes\[100101 100102 100103 200001 300001\]`
+
+import (
+	"a"
+
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+type coder int
+
+func (c coder) Code() int { return int(c) }
+
+func init() {
+	errors.MustRegister(coder(a.ErrUserNotFound)) // want `duplicate error code 100101, already registered at .*a.go:18:\d+`
+	errors.MustRegister(coder(300001))
+}
+
+func use() error {
+	return errors.WithCode(a.ErrUserExists, "exists")
+}
@@ -0,0 +1,34 @@
+package c // want package:`codes\[300001 300002 300003 300004\] modules\[order=300000-300099\]`
+
+import (
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+type coder int
+
+func (c coder) Code() int { return int(c) }
+
+func init() {
+	errors.MustRegister(coder(300001))
+}
+
+type notFound struct{}
+
+func (notFound) Code() int { return 300002 }
+
+func init() {
+	errors.Register(notFound{})
+	_ = errors.WithCode(300002, "not found")
+}
+
+var ErrConflict = errors.NewCode(300003, 409, "Conflict", "")
+
+var ErrDuplicate = errors.NewCode(300003, 409, "Conflict", "") // want `duplicate error code 300003, already registered at .*c.go:24:\d+`
+
+var order = errors.NewModule("order", 300000, 300099)
+
+var ErrOrderClosed = order.NewCode(300004, 409, "Order closed", "")
+
+func close() error {
+	return errors.WithCode(300004, "closed")
+}
@@ -0,0 +1,6 @@
+package d // want package:`codes\[100101 100102 100103 200001 300001 300002 300003 300004\] modules\[order=300000-300099\]`
+
+import (
+	_ "b"
+	_ "c" // want `error code 300001 is registered at both .*b.go:15:\d+ \(via b\) and .*c.go:12:\d+ \(via c\)`
+)
@@ -0,0 +1,27 @@
+package e // want package:`codes\[400001 400002\]`
+
+import "github.com/leilei3167/basic/pkg/errors"
+
+type pair struct {
+	HTTP int
+	C    int
+}
+
+func (p pair) Code() int { return p.C }
+
+func newCoder(httpStatus, code int) errors.Coder { return pair{httpStatus, code} }
+
+func init() {
+	errors.MustRegister(pair{HTTP: 500, C: 400001})
+	errors.Register(errors.WithRetry(pair{C: 400002}, 0))
+
+	//无法确定错误码的注册不应误报
+	errors.MustRegister(pair{500, 400003})
+	errors.MustRegister(newCoder(404, 400004))
+}
+
+func use() error {
+	//不属于任何已知模块的错误码可能在其他没有导入的包中注册
+	_ = errors.WithCode(999001, "registered elsewhere")
+	return errors.WrapC(nil, 400002, "retry")
+}
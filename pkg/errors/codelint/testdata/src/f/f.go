@@ -0,0 +1,13 @@
+package f // want package:`codes\[500001\] modules\[pay=500000-500099\]`
+
+import "github.com/leilei3167/basic/pkg/errors"
+
+var pay = errors.NewModule("pay", 500000, 500099)
+
+var ErrPayFailed = pay.NewCode(500001, 500, "Pay failed", "")
+
+func use() error {
+	_ = errors.WithCode(500001, "failed")
+	_ = errors.WithCode(999002, "registered elsewhere")
+	return errors.WithCode(500002, "refund") // want `error code 500002 is never registered`
+}
@@ -0,0 +1,11 @@
+package g // want package:`codes\[500001\] modules\[pay=500000-500099\]`
+
+import (
+	_ "f"
+
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+func use() error {
+	return errors.WrapC(nil, 500003, "refund") // want `error code 500003 is never registered`
+}
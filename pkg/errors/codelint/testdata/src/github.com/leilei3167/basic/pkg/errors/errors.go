@@ -0,0 +1,47 @@
+package errors
+
+import "time"
+
+type Coder interface {
+	Code() int
+}
+
+func Register(coder Coder)     {}
+func MustRegister(coder Coder) {}
+
+type RetryableCoder interface {
+	Coder
+	Retryable() bool
+}
+
+func WithRetry(coder Coder, after time.Duration) RetryableCoder { return nil }
+
+type Code struct{}
+
+type CodeValue interface {
+	~int | *Code
+}
+
+func WithCode[C CodeValue](code C, format string, args ...any) error { return nil }
+
+func WrapC[C CodeValue](err error, code C, format string, args ...any) error { return nil }
+
+type Depth int
+
+func (d Depth) WithCode(code int, format string, args ...any) error { return nil }
+
+func (d Depth) WrapC(err error, code int, format string, args ...any) error { return nil }
+
+func WithCodeDepth[C CodeValue](d Depth, code C, format string, args ...any) error { return nil }
+
+func WrapCDepth[C CodeValue](d Depth, err error, code C, format string, args ...any) error {
+	return nil
+}
+
+func NewCode(code, httpStatus int, message, reference string) *Code { return nil }
+
+type Module struct{}
+
+func NewModule(name string, min, max int) *Module { return nil }
+
+func (m *Module) NewCode(code, httpStatus int, message, reference string) *Code { return nil }
@@ -9,6 +9,7 @@ import (
 type baseError struct {
 	msg string
 	*stack
+	frames textFrames //从其他进程还原时携带的文本堆栈
 }
 
 func (b *baseError) Error() string {
@@ -20,33 +21,40 @@ func (f *baseError) Format(s fmt.State, verb rune) {
 		if s.Flag('+') {
 			io.WriteString(s, f.msg)
 			f.stack.Format(s, verb) //将堆栈格式化
+			f.frames.Format(s, verb)
 			return
 		}
 		fallthrough
-	case 's':
-		io.WriteString(s, f.msg)
-	case 'q':
-		fmt.Fprintf(s, "%q", f.msg)
+	default:
+		formatText(s, verb, f, f.msg)
 	}
 }
 
 func New(msg string) error {
 	return &baseError{
 		msg:   msg,
-		stack: callers(),
+		stack: callers(StackDepth()),
 	}
 }
 
 func Errorf(format string, args ...any) error {
 	return &baseError{
 		msg:   fmt.Sprintf(format, args...),
-		stack: callers(),
+		stack: callers(StackDepth()),
 	}
 }
 
+// Sentinel 创建一个不采集堆栈的错误,适合作为包级别的哨兵错误或在热点路径上使用,example:
+//
+//	var ErrRowEmpty = errors.Sentinel("row is empty")
+func Sentinel(msg string) error {
+	return &baseError{msg: msg}
+}
+
 type withStack struct {
 	error
 	*stack
+	frames textFrames
 }
 
 func (w *withStack) Cause() error { return w.error }
@@ -63,14 +71,13 @@ func (w *withStack) Format(s fmt.State, verb rune) {
 	case 'v':
 		if s.Flag('+') {
 			fmt.Fprintf(s, "%+v", w.Cause())
-			w.stack.Format(s, verb)
+			w.stack.format(s, verb, innerStack(w.Cause()))
+			w.frames.Format(s, verb)
 			return
 		}
 		fallthrough
-	case 's':
-		io.WriteString(s, w.Error())
-	case 'q':
-		fmt.Fprintf(s, "%q", w.Error())
+	default:
+		formatText(s, verb, w, w.Error())
 	}
 }
 
@@ -92,8 +99,8 @@ func (w *withMessage) Format(s fmt.State, verb rune) {
 			return
 		}
 		fallthrough
-	case 's', 'q':
-		io.WriteString(s, w.Error())
+	default:
+		formatText(s, verb, w, w.Error())
 	}
 }
 
@@ -123,74 +130,134 @@ type withCode struct {
 	code  int
 	cause error
 	*stack
+	frames textFrames
+	remote Coder //由Remote还原的错误携带的Coder,错误码未在本进程注册时使用
 }
 
-func (w *withCode) Error() string { return fmt.Sprintf("%v", w) }
 func (w *withCode) Cause() error  { return w.cause }
 func (w *withCode) Unwrap() error { return w.cause }
 
-//WithCode 根据业务错误码,创建一个带错误码的错误
-func WithCode(code int, format string, args ...any) error {
-	return &withCode{
-		err:   fmt.Errorf(format, args...),
-		code:  code,
-		cause: nil,
-		stack: callers(),
+// Is 使NewCode创建的哨兵可以作为errors.Is的target,错误码相同即视为匹配
+func (w *withCode) Is(target error) bool {
+	if c, ok := target.(*Code); ok {
+		return w.code == c.code
 	}
+	return false
+}
+
+//WithCode 根据业务错误码,创建一个带错误码的错误,code可以是整型错误码或者NewCode创建的哨兵
+func WithCode[C CodeValue](code C, format string, args ...any) error {
+	return newWithCode(fmt.Errorf(format, args...), codeValue(code), nil, callers(StackDepth()))
 }
 
 func Wrap(err error, message string) error {
 	if err == nil {
 		return nil
 	}
-	//判断是否是带错误码的错误类型,是的话需要保留错误码等信息
-	if e, ok := err.(*withCode); ok {
-		return &withCode{
-			err:   fmt.Errorf(message),
-			code:  e.code,
-			cause: err,
-			stack: callers(),
-		}
-	}
-	err = &withMessage{cause: err, msg: message}
-	return &withStack{err, callers()}
+	return wrap(err, message, callers(StackDepth()))
 }
 
 func Wrapf(err error, format string, args ...any) error {
 	if err == nil {
 		return nil
 	}
+	return wrap(err, fmt.Sprintf(format, args...), callers(StackDepth()))
+}
+
+func WrapC[C CodeValue](err error, code C, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return newWithCode(fmt.Errorf(format, args...), codeValue(code), err, callers(StackDepth()))
+}
+
+// Remote 还原由其他服务返回的带错误码的错误,如gRPC status,http错误响应,
+// 不调用CodeHook也不采集堆栈;错误码未在本进程注册时,ParseCoder返回由参数组成的Coder,
+// 使http状态码和参考文档不会丢失,cause可以为nil
+func Remote(code, httpStatus int, message, reference string, cause error) error {
+	return &withCode{
+		err:    stderrors.New(message),
+		code:   code,
+		cause:  cause,
+		remote: &Code{code: code, http: httpStatus, ext: message, ref: reference},
+	}
+}
 
+//newWithCode 创建新的带错误码的错误并调用CodeHook
+func newWithCode(err error, code int, cause error, st *stack) *withCode {
+	runHooks(code)
+	return &withCode{err: err, code: code, cause: cause, stack: st}
+}
+
+//wrap 是Wrap和Wrapf的实现,st需要由导出的函数采集,以保证堆栈从用户调用处开始
+func wrap(err error, message string, st *stack) error {
+	//判断是否是带错误码的错误类型,是的话需要保留错误码等信息
 	if e, ok := err.(*withCode); ok {
 		return &withCode{
-			err:   fmt.Errorf(format, args...),
-			code:  e.code,
-			cause: err,
-			stack: callers(),
+			err:    stderrors.New(message),
+			code:   e.code,
+			cause:  err,
+			stack:  st,
+			remote: e.remote, //Remote还原的错误码未在本进程注册,需要保留其Coder
 		}
 	}
+	err = &withMessage{cause: err, msg: message}
+	return &withStack{error: err, stack: st}
+}
 
-	err = &withMessage{
-		cause: err,
-		msg:   fmt.Sprintf(format, args...),
+// Depth 指定单次调用采集堆栈的深度,不受SetStackDepth的影响,NoStack表示不采集,example:
+//
+//	errors.Depth(errors.NoStack).WithCode(code, "invalid row %d", i)
+type Depth int
+
+func (d Depth) New(msg string) error {
+	return &baseError{msg: msg, stack: callers(int(d))}
+}
+
+func (d Depth) Errorf(format string, args ...any) error {
+	return &baseError{msg: fmt.Sprintf(format, args...), stack: callers(int(d))}
+}
+
+func (d Depth) Wrap(err error, message string) error {
+	if err == nil {
+		return nil
 	}
-	return &withStack{
-		err,
-		callers(),
+	return wrap(err, message, callers(int(d)))
+}
+
+func (d Depth) Wrapf(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
 	}
+	return wrap(err, fmt.Sprintf(format, args...), callers(int(d)))
 }
 
-func WrapC(err error, code int, format string, args ...any) error {
+//WithCode 只接受整型错误码,需要使用NewCode创建的哨兵时使用WithCodeDepth
+func (d Depth) WithCode(code int, format string, args ...any) error {
+	return newWithCode(fmt.Errorf(format, args...), code, nil, callers(int(d)))
+}
+
+//WrapC 只接受整型错误码,需要使用NewCode创建的哨兵时使用WrapCDepth
+func (d Depth) WrapC(err error, code int, format string, args ...any) error {
 	if err == nil {
 		return nil
 	}
+	return newWithCode(fmt.Errorf(format, args...), code, err, callers(int(d)))
+}
 
-	return &withCode{
-		err:   fmt.Errorf(format, args...),
-		code:  code,
-		cause: err,
-		stack: callers(),
+// WithCodeDepth 同Depth.WithCode,code可以是整型错误码或者NewCode创建的哨兵,example:
+//
+//	errors.WithCodeDepth(errors.NoStack, ErrUserNotFound, "user %q not found", name)
+func WithCodeDepth[C CodeValue](d Depth, code C, format string, args ...any) error {
+	return newWithCode(fmt.Errorf(format, args...), codeValue(code), nil, callers(int(d)))
+}
+
+// WrapCDepth 同Depth.WrapC,code可以是整型错误码或者NewCode创建的哨兵
+func WrapCDepth[C CodeValue](d Depth, err error, code C, format string, args ...any) error {
+	if err == nil {
+		return nil
 	}
+	return newWithCode(fmt.Errorf(format, args...), codeValue(code), err, callers(int(d)))
 }
 
 //Cause 返回该错误的底层错误是哪一个
@@ -4,7 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+
+	"go.uber.org/zap/zapcore"
 )
 
 func TestNew(t *testing.T) {
@@ -76,3 +81,395 @@ func TestWrapf(t *testing.T) {
 		}
 	}
 }
+
+func TestStackDepth(t *testing.T) {
+	type stackTracer interface{ StackTrace() StackTrace }
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"sentinel", Sentinel("sentinel"), 0},
+		{"no stack", Depth(NoStack).New("no stack"), 0},
+		{"one frame", Depth(1).Errorf("one frame"), 1},
+		{"wrap one frame", Depth(1).Wrap(io.EOF, "wrap"), 1},
+		{"default", New("default"), -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := tt.err.(stackTracer).StackTrace()
+			if tt.want < 0 {
+				if len(st) < 2 {
+					t.Errorf("want the full stack, got %d frames", len(st))
+				}
+				return
+			}
+			if len(st) != tt.want {
+				t.Errorf("want %d frames, got %d", tt.want, len(st))
+			}
+			if tt.want > 0 && funcname(st[0].name()) != "TestStackDepth" {
+				t.Errorf("want the stack to start at the caller, got %s", st[0].name())
+			}
+			//没有堆栈时各种格式化方式都不应panic
+			_ = fmt.Sprintf("%+v %v %s %q", tt.err, tt.err, tt.err, tt.err)
+		})
+	}
+
+	t.Run("global", func(t *testing.T) {
+		defer SetStackDepth(StackDepth())
+		SetStackDepth(NoStack)
+		err := WithCode(1, "no stack")
+		if got := fmt.Sprintf("%-v", err); got == "" {
+			t.Errorf("want the error message, got empty string")
+		}
+		if st := err.(stackTracer).StackTrace(); len(st) != 0 {
+			t.Errorf("want no frames, got %d", len(st))
+		}
+	})
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.Run("default", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = New("row invalid")
+		}
+	})
+	b.Run("depth=1", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Depth(1).New("row invalid")
+		}
+	})
+	b.Run("nostack", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Depth(NoStack).New("row invalid")
+		}
+	})
+	b.Run("sentinel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Sentinel("row invalid")
+		}
+	})
+}
+
+func BenchmarkWithCode(b *testing.B) {
+	b.Run("default", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = WithCode(1, "row %d invalid", i)
+		}
+	})
+	b.Run("nostack", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Depth(NoStack).WithCode(1, "row %d invalid", i)
+		}
+	})
+}
+
+func newInner() error { return New("inner") }
+
+func wrapOuter() error { return Wrap(newInner(), "outer") }
+
+func TestStackOptions(t *testing.T) {
+	defer SetStackOptions(StackOptions{})
+
+	plain := fmt.Sprintf("%+v", wrapOuter())
+	if !strings.Contains(plain, "/pkg/errors/errors_test.go") || !strings.Contains(plain, "testing.tRunner") {
+		t.Fatalf("want full paths and testing frames without options, got:\n%s", plain)
+	}
+
+	_, file, _, _ := runtime.Caller(0)
+	SetStackOptions(StackOptions{
+		TrimPrefixes: []string{filepath.Dir(file)},
+		SkipRuntime:  true,
+		Collapse:     true,
+	})
+	got := fmt.Sprintf("%+v", wrapOuter())
+	if strings.Contains(got, filepath.Dir(file)) {
+		t.Errorf("want the prefix trimmed, got:\n%s", got)
+	}
+	if strings.Contains(got, "testing.tRunner") || strings.Contains(got, "runtime.goexit") {
+		t.Errorf("want runtime and testing frames dropped, got:\n%s", got)
+	}
+	if strings.Count(got, "TestStackOptions") != 1 {
+		t.Errorf("want frames shared with the wrapped error printed once, got:\n%s", got)
+	}
+	//共享的帧中只有TestStackOptions会被打印,runtime和testing的帧不计入
+	if !strings.Contains(got, "... 1 frames shared with the wrapped error") {
+		t.Errorf("want a note about the collapsed frames, got:\n%s", got)
+	}
+}
+
+func TestLogObject(t *testing.T) {
+	err := WrapC(Wrap(New("db timeout"), "query user"), 1, "get user %d", 42)
+
+	enc := zapcore.NewMapObjectEncoder()
+	if e := LogObject(err, true).MarshalLogObject(enc); e != nil {
+		t.Fatal(e)
+	}
+	if enc.Fields["code"] != 1 || enc.Fields["error"] != "get user 42" {
+		t.Errorf("unexpected fields: %v", enc.Fields)
+	}
+	if chain, ok := enc.Fields["chain"].([]any); !ok || len(chain) != 3 {
+		t.Errorf("want a chain of 3 errors, got %#v", enc.Fields["chain"])
+	}
+	if stack, _ := enc.Fields["stack"].(string); !strings.Contains(stack, "TestLogObject") {
+		t.Errorf("want the stack of the outermost error, got %q", stack)
+	}
+
+	enc = zapcore.NewMapObjectEncoder()
+	_ = err.(zapcore.ObjectMarshaler).MarshalLogObject(enc)
+	if _, ok := enc.Fields["stack"]; ok {
+		t.Errorf("stack should only be logged on request")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	const code = 140001
+	Register(defaultCoder{C: code, HTTP: 409, Ext: "Conflict", Ref: "ref"})
+
+	orig := WrapC(Wrap(New("duplicate key"), "insert user"), code, "create user %q", "foo")
+	data, err := Marshal(orig, WithFrames())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got error
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !IsCode(got, code) {
+		t.Errorf("IsCode: want %d on %v", code, got)
+	}
+	if coder := ParseCoder(got); coder.HTTPStatus() != 409 {
+		t.Errorf("ParseCoder: got %d, want 409", coder.HTTPStatus())
+	}
+	if cause := Cause(got); cause.Error() != "duplicate key" {
+		t.Errorf("Cause: got %q", cause)
+	}
+	for _, verb := range []string{"%v", "%-v", "%+v", "%#v", "%#+v"} {
+		if want, got := fmt.Sprintf(verb, orig), fmt.Sprintf(verb, got); want != got {
+			t.Errorf("%s:\nwant %s\ngot  %s", verb, want, got)
+		}
+	}
+	if want, got := fmt.Sprintf("%+v", Cause(orig)), fmt.Sprintf("%+v", Cause(got)); want != got {
+		t.Errorf("%%+v of cause:\nwant %s\ngot  %s", want, got)
+	}
+
+	//再次编码时保留还原出的堆栈
+	again, err := Marshal(got, WithFrames())
+	if err != nil || string(again) != string(data) {
+		t.Errorf("re-marshal: got %s, want %s", again, data)
+	}
+
+	var none error = io.EOF
+	if err := Unmarshal([]byte("null"), &none); err != nil || none != nil {
+		t.Errorf("want nil error from null, got %v, %v", none, err)
+	}
+}
+
+func TestSentinelCode(t *testing.T) {
+	unregister(t, 140101, 140102)
+	errNotFound := NewCode(140101, 404, "User not found", "")
+	errExists := NewCode(140102, 409, "User already exists", "")
+
+	err := Wrap(WrapC(io.EOF, errNotFound, "get user %d", 1), "handler")
+	if !Is(err, errNotFound) {
+		t.Errorf("want %v to match the sentinel", err)
+	}
+	if Is(err, errExists) {
+		t.Errorf("want %v not to match another sentinel", err)
+	}
+	if !IsCode(err, 140101) || ParseCoder(err) != Coder(errNotFound) {
+		t.Errorf("want the registered sentinel, got %v", ParseCoder(err))
+	}
+	if !Is(fmt.Errorf("wrapped: %w", WithCode(errExists, "exists")), errExists) {
+		t.Errorf("want errors.Is to walk through fmt.Errorf")
+	}
+	if ParseCoder(errExists).HTTPStatus() != 409 || errExists.Error() != "User already exists" {
+		t.Errorf("sentinel should be usable as an error and a coder")
+	}
+	if !Is(WithCodeDepth(NoStack, errNotFound, "no stack"), errNotFound) ||
+		!Is(WrapCDepth(1, io.EOF, errExists, "one frame"), errExists) {
+		t.Errorf("want the Depth variants to accept sentinels")
+	}
+	type userCode int //以int为底层类型的错误码
+	var f func(userCode, string, ...any) error = WithCode
+	if !IsCode(f(userCode(140101), "named int"), 140101) {
+		t.Errorf("want named int codes and function values to work")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("want NewCode to panic on a duplicate code")
+		}
+	}()
+	NewCode(140101, 404, "dup", "")
+}
+
+func panics(v any) (err error) {
+	defer Recover(&err)
+	if v == nil {
+		var m map[string]int
+		m["nil map"] = 1
+	}
+	panic(v)
+}
+
+func TestWrapRemote(t *testing.T) {
+	const code = 990001 //未在本进程注册
+	err := Wrapf(Wrap(Remote(code, 404, "Not found", "ref", nil), "get user"), "handle %d", 1)
+
+	coder := ParseCoder(err)
+	if coder.Code() != code || coder.HTTPStatus() != 404 || coder.Reference() != "ref" {
+		t.Errorf("ParseCoder: got %d/%d/%q, want %d/404/ref", coder.Code(), coder.HTTPStatus(), coder.Reference(), code)
+	}
+
+	data, err2 := Marshal(err)
+	if err2 != nil {
+		t.Fatal(err2)
+	}
+	var got error
+	if err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if coder := ParseCoder(got); coder.Code() != code || coder.HTTPStatus() != 404 {
+		t.Errorf("ParseCoder after Unmarshal: got %d/%d, want %d/404", coder.Code(), coder.HTTPStatus(), code)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		cause error
+	}{
+		{"string", "boom", nil},
+		{"error", io.EOF, io.EOF},
+		{"runtime error", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := panics(tt.value)
+			if !IsCode(err, PanicCode) || ParseCoder(err).HTTPStatus() != 500 {
+				t.Fatalf("want a coded panic error, got %#v", err)
+			}
+			if tt.cause != nil && !Is(err, tt.cause) {
+				t.Errorf("want the panic value %v in the chain", tt.cause)
+			}
+			st := err.(interface{ StackTrace() StackTrace }).StackTrace()
+			if len(st) == 0 || funcname(st[0].name()) != "panics" {
+				t.Errorf("want the stack to start at the panic site, got %v", st)
+			}
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	err := <-Go(func() error { panic("worker") })
+	if !IsCode(err, PanicCode) {
+		t.Errorf("want a coded panic error, got %v", err)
+	}
+	if err := <-Go(func() error { return io.EOF }); err != io.EOF {
+		t.Errorf("want the returned error, got %v", err)
+	}
+	if IsCode(New("x"), PanicCode) || IsCode(WithCode(990002, "x"), PanicCode) {
+		t.Error("unknown errors should not match PanicCode")
+	}
+}
+
+func TestModule(t *testing.T) {
+	user := declareModule(t, "test-user", 160100, 160199)
+	errNotFound := user.NewCode(160101, 404, "User not found", "")
+	user.MustRegister(defaultCoder{C: 160102, HTTP: 409, Ext: "User already exists"})
+
+	if err := user.Register(defaultCoder{C: 160200}); err == nil {
+		t.Errorf("want an error when registering outside the module range")
+	}
+
+	//默认不限制不经由模块注册的错误码,开启严格模式时报告已注册的越界错误码
+	unregister(t, 160400)
+	NewCode(160400, 400, "outside", "")
+	if err := strictModules(t); err == nil || !strings.Contains(err.Error(), "160400") {
+		t.Errorf("StrictModules: want 160400 reported, got %v", err)
+	}
+	NewCode(160103, 400, "Invalid user", "") //严格模式下不经由模块注册时同样检查范围
+	for _, register := range []func(){
+		func() { NewCode(160300, 400, "outside", "") },
+		func() { MustRegister(defaultCoder{C: 160301}) },
+		func() { Register(defaultCoder{C: 160302}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want a panic when registering outside every module")
+				}
+			}()
+			register()
+		}()
+	}
+	if m, ok := ModuleOf(160150); !ok || m != user {
+		t.Errorf("ModuleOf: got %v", m)
+	}
+
+	got := CodesByModule("test-user")
+	if len(got) != 3 || got[0] != Coder(errNotFound) || got[1].Code() != 160102 {
+		t.Errorf("CodesByModule: got %v", got)
+	}
+
+	prev := 0
+	for coder := range Coders() {
+		if coder.Code() <= prev {
+			t.Fatalf("Coders: want sorted codes, got %d after %d", coder.Code(), prev)
+		}
+		prev = coder.Code()
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("want NewModule to panic on an overlapping range")
+		}
+	}()
+	NewModule("test-order", 160150, 160250)
+}
+
+//unregister 在测试结束时移除错误码,使注册错误码的测试可以重复和乱序执行
+func unregister(t *testing.T, cs ...int) {
+	t.Cleanup(func() {
+		codeMux.Lock()
+		defer codeMux.Unlock()
+		for _, code := range cs {
+			delete(codes, code)
+		}
+	})
+}
+
+//declareModule 声明模块,测试结束时移除该模块及其范围内注册的错误码
+func declareModule(t *testing.T, name string, min, max int) *Module {
+	m := NewModule(name, min, max)
+	t.Cleanup(func() {
+		codeMux.Lock()
+		defer codeMux.Unlock()
+		for i, v := range modules {
+			if v == m {
+				modules = append(modules[:i:i], modules[i+1:]...)
+				break
+			}
+		}
+		for code := range codes {
+			if m.Contains(code) {
+				delete(codes, code)
+			}
+		}
+	})
+	return m
+}
+
+//strictModules 开启严格模式,测试结束时关闭
+func strictModules(t *testing.T) error {
+	t.Cleanup(func() {
+		codeMux.Lock()
+		strict = false
+		codeMux.Unlock()
+	})
+	return StrictModules()
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"sync/atomic"
 )
 
 //withCode 的格式化打印实现
@@ -14,18 +16,53 @@ type formatInfo struct {
 	message string
 	err     string
 	stack   *stack
+	frames  textFrames
+}
+
+// ErrorMode 决定withCode的Error()返回的内容,%s,%q,%x以及不带标志的%v与Error()一致
+type ErrorMode int32
+
+const (
+	// ModeExternal 返回注册错误码时指定的对外信息,为默认值
+	ModeExternal ErrorMode = iota
+	// ModeInternal 返回创建错误时指定的对内信息
+	ModeInternal
+	// ModeCombined 同时返回对内信息,错误码和对外信息,如"user 1 not found (100101: User not found)"
+	ModeCombined
+)
+
+var errorMode atomic.Int32
+
+// SetErrorMode 全局修改withCode的Error()返回的内容,可以在运行时调用
+func SetErrorMode(mode ErrorMode) {
+	errorMode.Store(int32(mode))
+}
+
+// GetErrorMode 返回当前的ErrorMode
+func GetErrorMode() ErrorMode {
+	return ErrorMode(errorMode.Load())
+}
+
+func (w *withCode) Error() string {
+	switch GetErrorMode() {
+	case ModeInternal:
+		return w.err.Error()
+	case ModeCombined:
+		return fmt.Sprintf("%s (%d: %s)", w.err.Error(), w.code, buildFormatInfo(w).message)
+	}
+	return buildFormatInfo(w).message
 }
 
 //Format 实现Formatter接口
-//%s,%v 打印用户安全的脱敏错误信息
-//标志:
+//%s,%q,%x,%X 以及不带标志的%v 输出Error(),宽度等标志与格式化字符串时一致
+//%v 支持的标志:
 //# 将错误信息以json格式打印,便于日志记录
 //- 打印调用者的信息
 //+ 打印整个调用堆栈
 
 func (w *withCode) Format(state fmt.State, verb rune) {
-	switch verb {
-	case 'v':
+	switch {
+	case verb == 'v' && (state.Flag('#') || state.Flag('-') || state.Flag('+')):
 		str := bytes.NewBuffer([]byte{})
 		jsonData := []map[string]any{}
 
@@ -67,9 +104,29 @@ func (w *withCode) Format(state fmt.State, verb rune) {
 		}
 		fmt.Fprintf(state, "%s", strings.Trim(str.String(), "\r\n\t"))
 	default:
-		finfo := buildFormatInfo(w)
-		fmt.Fprintf(state, finfo.message)
+		formatText(state, verb, w, w.Error())
+	}
+}
+
+//formatText 安全的按verb输出错误信息,text中的%不会被当做格式化指令,不支持的verb输出为%!d(*errors.withCode=text)
+func formatText(state fmt.State, verb rune, err error, text string) {
+	switch verb {
+	case 'v':
+		io.WriteString(state, text)
+	case 's', 'q', 'x', 'X':
+		fmt.Fprintf(state, fmt.FormatString(state, verb), text)
+	default:
+		fmt.Fprintf(state, "%%!%c(%T=%s)", verb, err, text)
+	}
+}
+
+//caller 返回错误创建处的文件,行号和函数名,没有堆栈时返回空字符串
+func (f *formatInfo) caller() string {
+	fr, ok := f.stack.frame()
+	if !ok {
+		return f.frames.caller()
 	}
+	return fmt.Sprintf("%s:%d (%s)", fr.displayFile(), fr.line(), fr.name())
 }
 
 //buildFormatInfo 将一个错误转换为待打印的结构
@@ -83,6 +140,7 @@ func buildFormatInfo(e error) *formatInfo {
 			message: err.msg,
 			err:     err.msg,
 			stack:   err.stack,
+			frames:  err.frames,
 		}
 	case *withStack:
 		finfo = &formatInfo{
@@ -90,9 +148,10 @@ func buildFormatInfo(e error) *formatInfo {
 			message: err.Error(),
 			err:     err.Error(),
 			stack:   err.stack,
+			frames:  err.frames,
 		}
 	case *withCode:
-		coder, ok := codes[err.code] //从中获取已注册的coder
+		coder, ok := err.coder() //从中获取已注册的coder
 		if !ok {
 			coder = unknownCoder
 		}
@@ -106,8 +165,15 @@ func buildFormatInfo(e error) *formatInfo {
 			message: extMsg,          //此处是对用户安全的信息(注册错误码时指定的)
 			err:     err.err.Error(), //此处是对内错误信息
 			stack:   err.stack,
+			frames:  err.frames,
 		}
 
+	case *Code:
+		finfo = &formatInfo{
+			code:    err.code,
+			message: err.ext,
+			err:     err.ext,
+		}
 	default:
 		finfo = &formatInfo{
 			code:    unknownCoder.Code(),
@@ -129,9 +195,8 @@ func format(k int, jsonData []map[string]any, str *bytes.Buffer, finfo *formatIn
 				"error":   finfo.err,
 			}
 			caller := fmt.Sprintf("#%d", k)
-			if finfo.stack != nil {
-				f := Frame((*finfo.stack)[0]) //只将最近的调用情况
-				caller = fmt.Sprintf("%s %s:%d (%s)", caller, f.file(), f.line(), f.name())
+			if c := finfo.caller(); c != "" { //只将最近的调用情况
+				caller = fmt.Sprintf("%s %s", caller, c)
 			}
 			data["caller"] = caller
 		} else { //不需要打印堆栈的话,打印错误信息即可
@@ -140,16 +205,15 @@ func format(k int, jsonData []map[string]any, str *bytes.Buffer, finfo *formatIn
 		jsonData = append(jsonData, data)
 	} else { //不以JSON输出
 		if flagDetail || flagTrace {
-			if finfo.stack != nil {
-				f := Frame((*finfo.stack)[0])
-				fmt.Fprintf(str, "%s%s - #%d [%s:%d (%s)](%d) %s",
-					sep, finfo.err, k, f.file(), f.line(), f.name(), finfo.code, finfo.message)
+			if c := finfo.caller(); c != "" {
+				fmt.Fprintf(str, "%s%s - #%d [%s](%d) %s",
+					sep, finfo.err, k, c, finfo.code, finfo.message)
 			} else { //没有记录堆栈
 				fmt.Fprintf(str, "%s%s - #%d %s", sep, finfo.err, k, finfo.message)
 			}
 
 		} else { //不需要打印堆栈
-			fmt.Fprintf(str, finfo.message)
+			str.WriteString(finfo.message)
 		}
 	}
 
@@ -0,0 +1,111 @@
+package errors
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+//消息中包含%,用于检查不会被当做格式化指令
+var errQuota = NewCode(140501, http.StatusTooManyRequests, "Quota 100% used", "")
+
+var (
+	reFile  = regexp.MustCompile(`[^\s"(\[]*/([^/\s]+\.(go|s)):\d+`)
+	reAddr  = regexp.MustCompile(`\+0x[0-9a-f]+`)
+	reFrame = regexp.MustCompile(`\n(\S+)\n\t\S+`)
+)
+
+//normalize 去掉输出中与机器和Go版本相关的路径及行号,%+v的堆栈只保留本仓库的帧,
+//testing和runtime等标准库的帧随Go版本和平台变化
+func normalize(s string) string {
+	s = reFrame.ReplaceAllStringFunc(s, func(frame string) string {
+		if strings.HasPrefix(frame, "\ngithub.com/leilei3167/basic/") {
+			return frame
+		}
+		return ""
+	})
+	s = reFile.ReplaceAllString(s, "${1}:N")
+	return reAddr.ReplaceAllString(s, "")
+}
+
+func TestFormatGolden(t *testing.T) {
+	errs := []struct {
+		name string
+		err  error
+	}{
+		{"New", New("new 50%")},
+		{"Sentinel", Sentinel("sentinel")},
+		{"Wrap", Wrap(New("cause"), "wrap")},
+		{"WithMessage", WithMessage(New("cause"), "message")},
+		{"WithCode", WithCode(errQuota, "user %d used 100%%", 1)},
+		{"WithCodeUnknown", WithCode(999998, "unregistered")},
+		{"WrapC", WrapC(New("cause"), errQuota, "wrapc")},
+		{"WrapCoded", Wrap(WithCode(errQuota, "inner"), "outer")},
+		{"WrapCChain", WrapC(WrapC(Sentinel("cause"), errQuota, "inner"), 999998, "outer")},
+	}
+	var flagSets []string
+	for i := 0; i < 8; i++ {
+		var f string
+		for j, c := range "#-+" {
+			if i&(1<<j) != 0 {
+				f += string(c)
+			}
+		}
+		flagSets = append(flagSets, f)
+	}
+
+	var buf bytes.Buffer
+	for _, e := range errs {
+		for _, verb := range "vsqxd" {
+			for _, flags := range flagSets {
+				directive := "%" + flags + string(verb)
+				fmt.Fprintf(&buf, "== %s %s\n%s\n", e.name, directive, normalize(fmt.Sprintf(directive, e.err)))
+			}
+		}
+	}
+
+	golden := filepath.Join("testdata", "format.golden")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output differs from %s, run go test -update and check the diff:\n%s", golden, buf.String())
+	}
+}
+
+func TestErrorMode(t *testing.T) {
+	defer SetErrorMode(ModeExternal)
+
+	err := WithCode(errQuota, "user %d used 100%%", 1)
+	tests := []struct {
+		mode ErrorMode
+		want string
+	}{
+		{ModeExternal, "Quota 100% used"},
+		{ModeInternal, "user 1 used 100%"},
+		{ModeCombined, "user 1 used 100% (140501: Quota 100% used)"},
+	}
+	for _, tt := range tests {
+		SetErrorMode(tt.mode)
+		if got := err.Error(); got != tt.want {
+			t.Errorf("mode %d: Error() = %q, want %q", tt.mode, got, tt.want)
+		}
+		if got := fmt.Sprintf("%v|%s", err, err); got != tt.want+"|"+tt.want {
+			t.Errorf("mode %d: %%v|%%s = %q", tt.mode, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package grpcerr 提供errors包中带错误码的错误与gRPC status之间的相互转换,
+// 以及配套的拦截器,使业务错误码能够跨越gRPC服务调用传递
+package grpcerr
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/leilei3167/basic/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Domain 是写入ErrorInfo详情中的错误域,用于识别由本包编码的status
+	Domain = "github.com/leilei3167/basic"
+
+	metaCode      = "code"
+	metaHTTP      = "http-status"
+	metaReference = "reference"
+)
+
+// ToStatus 将错误转换为gRPC status
+//
+// 带错误码的错误根据Coder的http状态码映射gRPC码,对外的安全信息作为status的message,
+// 业务错误码等信息以ErrorInfo详情附带,即使其内部包装了status也以外层的错误码为准;
+// 其余错误中包含status时原样返回该status
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	code, ok := errors.CodeOf(err)
+	if !ok {
+		if st, ok := status.FromError(err); ok {
+			return st
+		}
+		//非业务错误,统一作为内部错误
+		coder := errors.ParseCoder(err)
+		return status.New(codes.Internal, coder.String())
+	}
+
+	coder := errors.ParseCoder(err)
+	st := status.New(FromHTTPStatus(coder.HTTPStatus()), coder.String())
+	detail := &errdetails.ErrorInfo{
+		Reason: strconv.Itoa(code),
+		Domain: Domain,
+		Metadata: map[string]string{
+			metaCode:      strconv.Itoa(code),
+			metaHTTP:      strconv.Itoa(coder.HTTPStatus()),
+			metaReference: coder.Reference(),
+		},
+	}
+	if ds, err := st.WithDetails(detail); err == nil {
+		return ds
+	}
+	return st
+}
+
+// FromStatus 将gRPC status还原为带错误码的错误,使IsCode等方法在调用方依然可用,
+// 错误码未在调用方注册时,ParseCoder依然能够返回ToStatus写入的http状态码和参考文档
+//
+// status中没有本包写入的ErrorInfo时,返回status自身的错误
+func FromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != Domain {
+			continue
+		}
+		meta := info.GetMetadata()
+		code, err := strconv.Atoi(meta[metaCode])
+		if err != nil {
+			continue
+		}
+		httpStatus, _ := strconv.Atoi(meta[metaHTTP])
+		return errors.Remote(code, httpStatus, st.Message(), meta[metaReference], st.Err())
+	}
+	return st.Err()
+}
+
+// FromError 是FromStatus的快捷方式,非status的错误原样返回
+func FromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return FromStatus(st)
+}
+
+// FromHTTPStatus 将错误的http状态码映射为最接近的gRPC码,
+// 低于400的状态码(如以2xx注册的Coder)映射为codes.Unknown,保证错误不会被当作成功返回
+func FromHTTPStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499: //客户端主动关闭请求
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	switch {
+	case httpStatus >= 400 && httpStatus < 500:
+		return codes.FailedPrecondition
+	case httpStatus >= 500:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
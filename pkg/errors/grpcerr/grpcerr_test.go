@@ -0,0 +1,137 @@
+package grpcerr
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/leilei3167/basic/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const codeNotFound = 110001
+
+type testCoder struct{}
+
+func (testCoder) HTTPStatus() int   { return http.StatusNotFound }
+func (testCoder) String() string    { return "Resource not found" }
+func (testCoder) Reference() string { return "ref-110001" }
+func (testCoder) Code() int         { return codeNotFound }
+
+func init() {
+	errors.Register(testCoder{})
+}
+
+//healthServer 在每次调用时都返回带错误码的错误
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (healthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return nil, errors.WithCode(codeNotFound, "service %q not found", "foo")
+}
+
+func (healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, _ grpc_health_v1.Health_WatchServer) error {
+	return errors.WithCode(codeNotFound, "watch failed")
+}
+
+func newClient(t *testing.T) grpc_health_v1.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+		grpc.StreamInterceptor(StreamServerInterceptor()),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, healthServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestToStatus(t *testing.T) {
+	st := ToStatus(errors.WithCode(codeNotFound, "internal detail"))
+	if st.Code() != codes.NotFound {
+		t.Errorf("code: got %v, want %v", st.Code(), codes.NotFound)
+	}
+	if st.Message() != "Resource not found" {
+		t.Errorf("message: got %q", st.Message())
+	}
+
+	got := FromStatus(st)
+	if !errors.IsCode(got, codeNotFound) {
+		t.Errorf("FromStatus lost the business code: %v", got)
+	}
+
+	if st := ToStatus(errors.New("plain")); st.Code() != codes.Internal {
+		t.Errorf("plain error: got %v, want %v", st.Code(), codes.Internal)
+	}
+	//以非错误状态码注册的Coder不能被当作成功
+	ok := errors.Remote(120002, http.StatusOK, "Accepted", "", nil)
+	if st := ToStatus(ok); st.Code() == codes.OK {
+		t.Errorf("coder with status 200: got %v", st.Code())
+	}
+	if err := FromStatus(status.New(codes.Unavailable, "down")); status.Code(err) != codes.Unavailable {
+		t.Errorf("foreign status should pass through, got %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	//外层的业务错误码优先于内部包装的status
+	inner := status.Error(codes.Unavailable, "db down")
+	st := ToStatus(errors.WrapC(inner, codeNotFound, "load user"))
+	if st.Code() != codes.NotFound {
+		t.Errorf("wrapped status: got %v, want %v", st.Code(), codes.NotFound)
+	}
+
+	//调用方没有注册该错误码时,http状态码和参考文档依然保留
+	remote := errors.Remote(120001, http.StatusConflict, "Order already paid", "ref-120001", nil)
+	for i, err := range []error{errors.WithCode(codeNotFound, "detail"), remote} {
+		want := errors.ParseCoder(err)
+		got := errors.ParseCoder(FromStatus(ToStatus(err)))
+		if got.Code() != want.Code() || got.HTTPStatus() != want.HTTPStatus() ||
+			got.String() != want.String() || got.Reference() != want.Reference() {
+			t.Errorf("%d: got coder %d %d %q %q, want %d %d %q %q", i,
+				got.Code(), got.HTTPStatus(), got.String(), got.Reference(),
+				want.Code(), want.HTTPStatus(), want.String(), want.Reference())
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	client := newClient(t)
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if !errors.IsCode(err, codeNotFound) {
+		t.Errorf("unary: want code %d, got %#v", codeNotFound, err)
+	}
+	if coder := errors.ParseCoder(err); coder.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("unary: want http status %d, got %d", http.StatusNotFound, coder.HTTPStatus())
+	}
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	_, err = stream.Recv()
+	if !errors.IsCode(err, codeNotFound) {
+		t.Errorf("stream: want code %d, got %#v", codeNotFound, err)
+	}
+}
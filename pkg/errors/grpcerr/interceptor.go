@@ -0,0 +1,73 @@
+package grpcerr
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor 将handler返回的错误转换为携带业务错误码的status
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor 是UnaryServerInterceptor的流式版本
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+// UnaryClientInterceptor 将服务端返回的status还原为带错误码的错误
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor 是UnaryClientInterceptor的流式版本,流上每次收发返回的错误都会被还原
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromError(err)
+		}
+		return &clientStream{ClientStream: cs}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	return convert(s.ClientStream.SendMsg(m))
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	return convert(s.ClientStream.RecvMsg(m))
+}
+
+func (s *clientStream) CloseSend() error {
+	return convert(s.ClientStream.CloseSend())
+}
+
+//convert 保留io.EOF,流的调用方依赖它判断流的结束
+func convert(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return FromError(err)
+}
@@ -0,0 +1,46 @@
+package errors
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// CodeHook 在带错误码的错误被创建时调用,用于统计等用途,必须是并发安全且足够轻量的
+type CodeHook func(code, httpStatus int)
+
+var (
+	hookMux sync.Mutex
+	hooks   atomic.Pointer[[]CodeHook]
+)
+
+// AddCodeHook 添加一个在WithCode,WrapC以及Recover创建错误时调用的hook,
+// Wrap/Wrapf包装已有的带错误码的错误以及Remote还原对端的错误时不会调用
+func AddCodeHook(hook CodeHook) {
+	hookMux.Lock()
+	defer hookMux.Unlock()
+
+	var hs []CodeHook
+	if prev := hooks.Load(); prev != nil {
+		hs = append(hs, *prev...)
+	}
+	hs = append(hs, hook)
+	hooks.Store(&hs)
+}
+
+//runHooks 在没有hook时只有一次原子读取的开销
+func runHooks(code int) {
+	hs := hooks.Load()
+	if hs == nil {
+		return
+	}
+	codeMux.Lock()
+	coder, ok := codes[code]
+	codeMux.Unlock()
+	if !ok {
+		coder = unknownCoder
+	}
+	status := coder.HTTPStatus()
+	for _, h := range *hs {
+		h(code, status)
+	}
+}
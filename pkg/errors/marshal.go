@@ -0,0 +1,158 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+)
+
+//错误的序列化,用于在消息队列,任务结果等进程边界之间传递完整的错误链
+
+const (
+	kindBase    = "base"
+	kindStack   = "stack"
+	kindMessage = "message"
+	kindCode    = "code"
+	kindError   = "error" //非本包创建的错误,只保留其错误信息
+)
+
+type encodedError struct {
+	Chain []encodedLayer `json:"chain"`
+}
+
+type encodedLayer struct {
+	Kind    string        `json:"kind"`
+	Code    int           `json:"code,omitempty"`
+	Message string        `json:"message"`
+	Frames  []string      `json:"frames,omitempty"`
+	Remote  *encodedCoder `json:"remote,omitempty"`
+}
+
+//encodedCoder 是Remote还原的错误携带的Coder,错误码未在本进程注册时需要随错误一起传递
+type encodedCoder struct {
+	HTTP    int    `json:"http"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`
+}
+
+type marshalOptions struct {
+	frames bool
+}
+
+// MarshalOption 控制Marshal的行为
+type MarshalOption func(*marshalOptions)
+
+// WithFrames 同时编码每一层错误的堆栈,堆栈以Frame.MarshalText的文本形式保存
+func WithFrames() MarshalOption {
+	return func(o *marshalOptions) {
+		o.frames = true
+	}
+}
+
+// Marshal 将整条错误链编码为JSON,由外到内记录每一层的类型,信息和错误码
+func Marshal(err error, opts ...MarshalOption) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	o := &marshalOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var enc encodedError
+	for err != nil {
+		var layer encodedLayer
+		switch e := err.(type) {
+		case *baseError:
+			layer = encodedLayer{Kind: kindBase, Message: e.msg, Frames: o.encodeFrames(e.stack, e.frames)}
+			err = nil
+		case *withStack:
+			layer = encodedLayer{Kind: kindStack, Frames: o.encodeFrames(e.stack, e.frames)}
+			err = e.error
+		case *withMessage:
+			layer = encodedLayer{Kind: kindMessage, Message: e.msg}
+			err = e.cause
+		case *withCode:
+			layer = encodedLayer{Kind: kindCode, Code: e.code, Message: e.err.Error(),
+				Frames: o.encodeFrames(e.stack, e.frames)}
+			if e.remote != nil {
+				coder, _ := e.coder()
+				layer.Remote = &encodedCoder{HTTP: coder.HTTPStatus(), Message: coder.String(), Ref: coder.Reference()}
+			}
+			err = e.cause
+		default:
+			layer = encodedLayer{Kind: kindError, Message: e.Error()}
+			err = nil
+		}
+		enc.Chain = append(enc.Chain, layer)
+	}
+	return json.Marshal(enc)
+}
+
+func (o *marshalOptions) encodeFrames(st *stack, frames textFrames) []string {
+	if !o.frames {
+		return nil
+	}
+	if len(frames) > 0 { //已经是还原出来的错误,原样保留
+		return frames
+	}
+	var ret []string
+	for _, f := range st.StackTrace() {
+		text, _ := f.MarshalText()
+		ret = append(ret, string(text))
+	}
+	return ret
+}
+
+// Unmarshal 将Marshal的结果还原为错误并写入target,还原的错误支持IsCode,ParseCoder,Cause
+// 以及%+v,%#v等格式化方式,example:
+//
+//	var jobErr error
+//	if err := errors.Unmarshal(data, &jobErr); err != nil {...}
+func Unmarshal(data []byte, target *error) error {
+	var enc *encodedError
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return err
+	}
+	if enc == nil || len(enc.Chain) == 0 {
+		*target = nil
+		return nil
+	}
+
+	//由内向外重建
+	var err error
+	for i := len(enc.Chain) - 1; i >= 0; i-- {
+		layer := enc.Chain[i]
+		last := i == len(enc.Chain)-1
+		switch layer.Kind {
+		case kindBase, kindError:
+			if !last {
+				return fmt.Errorf("errors: %q must be the innermost layer", layer.Kind)
+			}
+			if layer.Kind == kindError {
+				err = stderrors.New(layer.Message)
+			} else {
+				err = &baseError{msg: layer.Message, frames: layer.Frames}
+			}
+		case kindStack, kindMessage:
+			if last {
+				return fmt.Errorf("errors: %q can not be the innermost layer", layer.Kind)
+			}
+			if layer.Kind == kindStack {
+				err = &withStack{error: err, frames: layer.Frames}
+			} else {
+				err = &withMessage{cause: err, msg: layer.Message}
+			}
+		case kindCode:
+			w := &withCode{err: stderrors.New(layer.Message), code: layer.Code, cause: err, frames: layer.Frames}
+			if r := layer.Remote; r != nil {
+				w.remote = &Code{code: layer.Code, http: r.HTTP, ext: r.Message, ref: r.Ref}
+			}
+			err = w
+		default:
+			return fmt.Errorf("errors: unknown layer kind %q", layer.Kind)
+		}
+	}
+	*target = err
+	return nil
+}
@@ -0,0 +1,200 @@
+package errors
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+)
+
+//错误码的模块命名空间,每个模块保留一段错误码范围,如 user = 100100-100199, order = 100200-100299
+
+// Module 是错误码的命名空间,拥有一段保留的错误码范围[Min, Max]
+type Module struct {
+	name     string
+	min, max int
+}
+
+var (
+	modules []*Module //由codeMux保护
+	strict  bool      //由codeMux保护,为true时不经由模块注册的错误码也需要在某个模块的范围内
+)
+
+// NewModule 声明一个模块及其保留的错误码范围,名称重复或者与已有模块的范围重叠时panic,example:
+//
+//	var user = errors.NewModule("user", 100100, 100199)
+//	var ErrUserNotFound = user.NewCode(100101, http.StatusNotFound, "User not found", "")
+func NewModule(name string, min, max int) *Module {
+	if name == "" || min <= 0 || min > max {
+		panic(fmt.Sprintf("invalid module %q with range [%d, %d]", name, min, max))
+	}
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	for _, m := range modules {
+		if m.name == name {
+			panic(fmt.Sprintf("module %q already exists", name))
+		}
+		if min <= m.max && m.min <= max {
+			panic(fmt.Sprintf("range [%d, %d] of module %q overlaps with module %q [%d, %d]",
+				min, max, name, m.name, m.min, m.max))
+		}
+	}
+	m := &Module{name: name, min: min, max: max}
+	modules = append(modules, m)
+	return m
+}
+
+func (m *Module) Name() string { return m.name }
+func (m *Module) Min() int     { return m.min }
+func (m *Module) Max() int     { return m.max }
+
+// Contains 判断错误码是否在模块的范围内
+func (m *Module) Contains(code int) bool {
+	return code >= m.min && code <= m.max
+}
+
+// Register 在模块内注册错误码,错误码超出模块范围或者已被注册时返回错误
+func (m *Module) Register(coder Coder) error {
+	if !m.Contains(coder.Code()) {
+		return fmt.Errorf("code %d is outside the range [%d, %d] of module %q", coder.Code(), m.min, m.max, m.name)
+	}
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	if _, ok := codes[coder.Code()]; ok {
+		return fmt.Errorf("code %d is already registered", coder.Code())
+	}
+	codes[coder.Code()] = coder
+	return nil
+}
+
+// MustRegister 同Register,失败时panic
+func (m *Module) MustRegister(coder Coder) {
+	if err := m.Register(coder); err != nil {
+		panic(err.Error())
+	}
+}
+
+// NewCode 在模块内创建并注册一个错误码哨兵,失败时panic
+func (m *Module) NewCode(code, httpStatus int, message, reference string) *Code {
+	c := &Code{code: code, http: httpStatus, ext: message, ref: reference}
+	m.MustRegister(c)
+	return c
+}
+
+// Codes 返回模块范围内所有已注册的Coder,按错误码排序
+func (m *Module) Codes() []Coder {
+	var ret []Coder
+	for coder := range Coders() {
+		if m.Contains(coder.Code()) {
+			ret = append(ret, coder)
+		}
+	}
+	return ret
+}
+
+// Modules 返回所有已声明的模块,按范围排序
+func Modules() []*Module {
+	codeMux.Lock()
+	ret := append([]*Module(nil), modules...)
+	codeMux.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].min < ret[j].min })
+	return ret
+}
+
+// StrictModules 开启严格模式:此后通过Register,MustRegister,NewCode注册的错误码也必须在某个已声明模块的范围内,
+// 预留的内部错误码(UnknownCode,PanicCode,BadRequestCode)除外;返回开启前已注册但不属于任何模块的错误码,
+// 应在所有模块声明之后调用,如main的开头,example:
+//
+//	if err := errors.StrictModules(); err != nil {
+//		log.Fatal(err)
+//	}
+func StrictModules() error {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+	strict = true
+
+	var outside []int
+	for code := range codes {
+		if checkModules(code) != nil {
+			outside = append(outside, code)
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	sort.Ints(outside)
+	return fmt.Errorf("codes %v are outside the range of every module", outside)
+}
+
+//checkRange 检查不经由模块注册的错误码,严格模式下错误码必须在某个模块的范围内,调用方需要持有codeMux
+func checkRange(code int) error {
+	if !strict {
+		return nil
+	}
+	return checkModules(code)
+}
+
+//checkModules 检查错误码是否在某个模块的范围内,调用方需要持有codeMux
+func checkModules(code int) error {
+	switch code {
+	case UnknownCode, PanicCode, BadRequestCode:
+		return nil
+	}
+	for _, m := range modules {
+		if m.Contains(code) {
+			return nil
+		}
+	}
+	return fmt.Errorf("code %d is outside the range of every module", code)
+}
+
+// ModuleOf 返回错误码所属的模块
+func ModuleOf(code int) (*Module, bool) {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	for _, m := range modules {
+		if m.Contains(code) {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// CodesByModule 返回指定名称的模块内所有已注册的Coder,按错误码排序,模块不存在时返回nil
+func CodesByModule(name string) []Coder {
+	for _, m := range Modules() {
+		if m.name == name {
+			return m.Codes()
+		}
+	}
+	return nil
+}
+
+// ListCoders 返回所有已注册的Coder,按错误码排序
+func ListCoders() []Coder {
+	codeMux.Lock()
+	ret := make([]Coder, 0, len(codes))
+	for _, coder := range codes {
+		ret = append(ret, coder)
+	}
+	codeMux.Unlock()
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Code() < ret[j].Code() })
+	return ret
+}
+
+// Coders 按错误码从小到大遍历所有已注册的Coder,example:
+//
+//	for coder := range errors.Coders() {...}
+func Coders() iter.Seq[Coder] {
+	return func(yield func(Coder) bool) {
+		for _, coder := range ListCoders() {
+			if !yield(coder) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// PanicCode 由panic转换而来的错误所使用的错误码,与UnknownCode一样是预留的内部错误码,
+// 但可以与普通的未注册错误区分开
+const PanicCode = 2
+
+// Recover 将panic转换为带错误码(PanicCode)的错误写入errp,错误携带panic发生处的堆栈,
+// 必须直接以defer的方式调用,example:
+//
+//	func handle() (err error) {
+//		defer errors.Recover(&err)
+//		...
+//	}
+func Recover(errp *error) {
+	if r := recover(); r != nil {
+		*errp = fromPanic(r, panicStack())
+	}
+}
+
+// Protect 返回一个会将fn中的panic转换为错误的函数,适合与errgroup.Go等配合使用
+func Protect(fn func() error) func() error {
+	return func() (err error) {
+		defer Recover(&err)
+		return fn()
+	}
+}
+
+// Go 在新的goroutine中执行fn,fn中的panic会被转换为错误而不会导致进程崩溃,
+// 返回的channel会收到fn的执行结果
+func Go(fn func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- Protect(fn)()
+	}()
+	return ch
+}
+
+func fromPanic(r any, st *stack) error {
+	if err, ok := r.(error); ok {
+		return newWithCode(fmt.Errorf("panic: %v", err), PanicCode, err, st)
+	}
+	return newWithCode(fmt.Errorf("panic: %v", r), PanicCode, nil, st)
+}
+
+//panicStack 采集panic发生处的堆栈,跳过Recover自身以及runtime中处理panic的帧
+func panicStack() *stack {
+	depth := StackDepth()
+	if depth == NoStack {
+		return nil
+	}
+	var pcs [MaxStackDepth]uintptr
+	n := runtime.Callers(3, pcs[:]) //跳过runtime.Callers,panicStack,Recover
+	start := 0
+	for i, pc := range pcs[:n] {
+		fn := runtime.FuncForPC(pc - 1)
+		if fn != nil && fn.Name() == "runtime.gopanic" {
+			start = i + 1
+			break
+		}
+	}
+	//运行时错误(如空指针)在gopanic之后还有runtime.sigpanic等帧
+	for start < n {
+		fn := runtime.FuncForPC(pcs[start] - 1)
+		if fn == nil || !strings.HasPrefix(fn.Name(), "runtime.") {
+			break
+		}
+		start++
+	}
+	end := start + depth
+	if end > n {
+		end = n
+	}
+	var st stack = make([]uintptr, end-start)
+	copy(st, pcs[start:end])
+	return &st
+}
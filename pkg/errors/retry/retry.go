@@ -0,0 +1,129 @@
+// Package retry 提供一个遵循errors包重试声明的重试工具,支持指数退避,随机抖动以及context取消
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+type options struct {
+	attempts   int
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+	jitter     float64
+	retryIf    func(error) bool
+}
+
+// Option 用于修改重试的策略
+type Option func(*options)
+
+// Attempts 最多执行的次数(包括第一次),小于1时视为1
+func Attempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+// Backoff 设置第一次重试前的等待时间以及等待时间的上限
+func Backoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.initial = initial
+		o.max = max
+	}
+}
+
+// Multiplier 每次重试后等待时间的增长倍数
+func Multiplier(m float64) Option {
+	return func(o *options) {
+		o.multiplier = m
+	}
+}
+
+// Jitter 在等待时间上增加的随机抖动比例,取值[0,1],避免大量客户端同时重试
+func Jitter(f float64) Option {
+	return func(o *options) {
+		o.jitter = f
+	}
+}
+
+// RetryIf 自定义哪些错误需要重试,默认使用errors.IsRetryable
+func RetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retryIf = fn
+	}
+}
+
+func defaultOptions() *options {
+	return &options{
+		attempts:   3,
+		initial:    100 * time.Millisecond,
+		max:        10 * time.Second,
+		multiplier: 2,
+		jitter:     0.2,
+		retryIf:    errors.IsRetryable,
+	}
+}
+
+// Do 执行fn,当其返回可重试的错误时按照退避策略重试
+//
+// 等待时间取退避时间与错误声明的 errors.RetryAfter 中的较大者;
+// 不可重试的错误,次数耗尽或ctx被取消时,返回最后一次执行的错误
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.attempts < 1 {
+		o.attempts = 1
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < o.attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt == o.attempts-1 || !o.retryIf(err) {
+			break
+		}
+
+		timer := time.NewTimer(o.delay(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+//delay 计算第attempt次重试之前需要等待的时间
+func (o *options) delay(attempt int, err error) time.Duration {
+	d := float64(o.initial)
+	for i := 0; i < attempt; i++ {
+		d *= o.multiplier
+		if o.max > 0 && d > float64(o.max) {
+			d = float64(o.max)
+			break
+		}
+	}
+	if o.jitter > 0 {
+		d += d * o.jitter * rand.Float64()
+	}
+	if o.max > 0 && d > float64(o.max) {
+		d = float64(o.max)
+	}
+
+	if after := errors.RetryAfter(err); after > time.Duration(d) {
+		return after
+	}
+	return time.Duration(d)
+}
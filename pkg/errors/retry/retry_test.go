@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/leilei3167/basic/pkg/errors"
+)
+
+const (
+	codeUnavailable = 120001
+	codeBadRequest  = 120002
+)
+
+type testCoder struct {
+	code int
+	http int
+}
+
+func (c testCoder) HTTPStatus() int   { return c.http }
+func (c testCoder) String() string    { return http.StatusText(c.http) }
+func (c testCoder) Reference() string { return "" }
+func (c testCoder) Code() int         { return c.code }
+
+func init() {
+	errors.Register(errors.WithRetry(testCoder{codeUnavailable, http.StatusServiceUnavailable}, 0))
+	errors.Register(testCoder{codeBadRequest, http.StatusBadRequest})
+}
+
+func TestDo(t *testing.T) {
+	fast := []Option{Attempts(4), Backoff(time.Millisecond, 5*time.Millisecond)}
+
+	tests := []struct {
+		name  string
+		err   error
+		calls int
+	}{
+		{"retryable", errors.WithCode(codeUnavailable, "db down"), 4},
+		{"wrapped retryable", errors.Wrap(errors.WithCode(codeUnavailable, "db down"), "query"), 4},
+		{"not retryable", errors.WithCode(codeBadRequest, "bad input"), 1},
+		{"plain error", errors.New("boom"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := Do(context.Background(), func(context.Context) error {
+				calls++
+				return tt.err
+			}, fast...)
+			if err != tt.err {
+				t.Errorf("want the last error %v, got %v", tt.err, err)
+			}
+			if calls != tt.calls {
+				t.Errorf("want %d calls, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestDoSucceedsAfterRetry(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.WithCode(codeUnavailable, "db down")
+		}
+		return nil
+	}, Backoff(time.Millisecond, time.Millisecond))
+	if err != nil || calls != 3 {
+		t.Errorf("want success after 3 calls, got %v after %d calls", err, calls)
+	}
+}
+
+func TestDoContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Do(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return errors.WithCode(codeUnavailable, "db down")
+	}, Attempts(10), Backoff(time.Hour, time.Hour))
+	if !errors.IsCode(err, codeUnavailable) || calls != 1 {
+		t.Errorf("want the last error after 1 call, got %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+)
+
+// RetryableCoder 是Coder的扩展,声明该错误码代表的错误是否可以重试
+type RetryableCoder interface {
+	Coder
+	// Retryable 该错误码代表的错误是否可以重试
+	Retryable() bool
+	// RetryAfter 建议的重试等待时间,为0时由调用方自行决定
+	RetryAfter() time.Duration
+}
+
+type retryableCoder struct {
+	Coder
+	after time.Duration
+}
+
+func (r retryableCoder) Retryable() bool           { return true }
+func (r retryableCoder) RetryAfter() time.Duration { return r.after }
+
+// WithRetry 将一个Coder声明为可重试,after为建议的重试等待时间,example:
+//
+//	errors.MustRegister(errors.WithRetry(myCoder, time.Second))
+func WithRetry(coder Coder, after time.Duration) RetryableCoder {
+	return retryableCoder{Coder: coder, after: after}
+}
+
+// IsRetryable 沿错误链查找重试的声明,以最外层的声明为准:
+// 已注册的RetryableCoder,或者实现了 Temporary() bool 的错误(如net.Error);
+// 链上没有任何声明或者是context的取消/超时错误时返回false
+func IsRetryable(err error) bool {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return false
+		}
+		if v, ok := err.(*withCode); ok {
+			if coder, ok := retryableOf(v); ok {
+				return coder.Retryable()
+			}
+			continue
+		}
+		if t, ok := err.(interface{ Temporary() bool }); ok {
+			return t.Temporary()
+		}
+	}
+	return false
+}
+
+// RetryAfter 返回错误链上最外层声明的建议重试等待时间,没有声明时返回0
+//
+// 除RetryableCoder外,也识别实现了 RetryAfter() time.Duration 的错误
+func RetryAfter(err error) time.Duration {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if v, ok := err.(*withCode); ok {
+			if coder, ok := retryableOf(v); ok && coder.Retryable() {
+				return coder.RetryAfter()
+			}
+			continue
+		}
+		if r, ok := err.(interface{ RetryAfter() time.Duration }); ok {
+			return r.RetryAfter()
+		}
+	}
+	return 0
+}
+
+//retryableOf 返回错误码对应的RetryableCoder,包括Remote还原的错误码
+func retryableOf(w *withCode) (RetryableCoder, bool) {
+	coder, ok := w.coder()
+	if !ok {
+		return nil, false
+	}
+	rc, ok := coder.(RetryableCoder)
+	return rc, ok
+}
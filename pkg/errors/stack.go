@@ -7,12 +7,67 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 )
 
 const (
-	stackDepth = 32
+	// DefaultStackDepth 默认采集的堆栈深度
+	DefaultStackDepth = 32
+	// MaxStackDepth 允许设置的最大堆栈深度
+	MaxStackDepth = 128
+	// NoStack 不采集堆栈,适用于热点路径(如逐行校验导入的数据)
+	NoStack = 0
 )
 
+var stackDepth int32 = DefaultStackDepth
+
+// StackOptions 控制 %+v 打印堆栈时的行为
+type StackOptions struct {
+	// TrimPrefixes 打印文件路径时去除的前缀,如模块的根目录或者GOPATH,按顺序匹配第一个
+	TrimPrefixes []string
+	// SkipRuntime 不打印runtime.*和testing.*的帧
+	SkipRuntime bool
+	// Collapse 嵌套包装的错误中,外层错误不再重复打印与被包装错误相同的帧
+	Collapse bool
+}
+
+var stackOptions atomic.Pointer[StackOptions]
+
+func init() {
+	stackOptions.Store(&StackOptions{})
+}
+
+// SetStackOptions 设置全局的堆栈打印选项
+func SetStackOptions(opts StackOptions) {
+	opts.TrimPrefixes = append([]string(nil), opts.TrimPrefixes...)
+	stackOptions.Store(&opts)
+}
+
+func loadStackOptions() *StackOptions {
+	return stackOptions.Load()
+}
+
+// SetStackDepth 设置全局的堆栈采集深度,对New,Errorf,Wrap,WithCode等所有构造函数生效,
+// 取值范围[NoStack, MaxStackDepth]
+func SetStackDepth(depth int) {
+	atomic.StoreInt32(&stackDepth, int32(clampDepth(depth)))
+}
+
+// StackDepth 返回当前全局的堆栈采集深度
+func StackDepth() int {
+	return int(atomic.LoadInt32(&stackDepth))
+}
+
+func clampDepth(depth int) int {
+	if depth < NoStack {
+		return NoStack
+	}
+	if depth > MaxStackDepth {
+		return MaxStackDepth
+	}
+	return depth
+}
+
 type Frame uintptr //代表堆栈中的一个帧,他的值是程序计数器+1
 
 func (f Frame) pc() uintptr { return uintptr(f) - 1 }
@@ -25,6 +80,30 @@ func (f Frame) file() string { //根据给定的帧,返回当前程序计数器
 	file, _ := fn.FileLine(f.pc())
 	return file
 }
+
+// displayFile 返回打印时使用的文件名,会去除StackOptions中配置的前缀
+func (f Frame) displayFile() string {
+	return trimFile(f.file())
+}
+
+func trimFile(file string) string {
+	for _, prefix := range loadStackOptions().TrimPrefixes {
+		if prefix != "" && strings.HasPrefix(file, prefix) {
+			return strings.TrimLeft(strings.TrimPrefix(file, prefix), "/")
+		}
+	}
+	return file
+}
+
+// hidden 判断该帧是否需要在打印时被过滤
+func (f Frame) hidden() bool {
+	if !loadStackOptions().SkipRuntime {
+		return false
+	}
+	name := f.name()
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "testing.")
+}
+
 func (f Frame) line() int { //根据给定的帧,返回当前程序计数器所在的函数函数所在行号
 	fn := runtime.FuncForPC(f.pc())
 	if fn == nil {
@@ -50,7 +129,7 @@ func (f Frame) Format(s fmt.State, verb rune) {
 			//打印堆栈信息
 			io.WriteString(s, f.name())
 			io.WriteString(s, "\n\t")
-			io.WriteString(s, f.file())
+			io.WriteString(s, f.displayFile())
 		default:
 			io.WriteString(s, path.Base(f.file())) //默认只打印文件的名称
 		}
@@ -90,6 +169,9 @@ func (st StackTrace) Format(s fmt.State, verb rune) {
 		case s.Flag('+'):
 			//打印所有的帧
 			for _, f := range st {
+				if f.hidden() {
+					continue
+				}
 				io.WriteString(s, "\n")
 				f.Format(s, verb)
 			}
@@ -115,33 +197,139 @@ func (st StackTrace) formatSlice(s fmt.State, verb rune) {
 	io.WriteString(s, "]")
 }
 
-//用于记录堆栈深度
+// 用于记录堆栈深度
 type stack []uintptr //代表调用堆栈,由许多个帧组成
 
-func callers() *stack {
-	var pcs [stackDepth]uintptr
-	n := runtime.Callers(3, pcs[:]) // skip first 3 frames (runtime.Callers + runtime.Callers)
-	var st stack = pcs[0:n]
+// callers 采集调用者的堆栈,depth为NoStack时不采集,返回nil
+func callers(depth int) *stack {
+	depth = clampDepth(depth)
+	if depth == NoStack {
+		return nil
+	}
+	var pcs [MaxStackDepth]uintptr
+	n := runtime.Callers(3, pcs[:depth]) // skip first 3 frames (runtime.Callers + runtime.Callers)
+	var st stack = make([]uintptr, n)
+	copy(st, pcs[:n])
 	return &st
 }
 
+// frame 返回最近的一帧,没有采集堆栈时返回false
+func (s *stack) frame() (Frame, bool) {
+	if s == nil || len(*s) == 0 {
+		return 0, false
+	}
+	return Frame((*s)[0]), true
+}
+
 func (s *stack) Format(st fmt.State, verb rune) {
+	if s == nil {
+		return
+	}
+	s.format(st, verb, nil)
+}
+
+// format 打印堆栈,inner为被包装错误的堆栈,开启Collapse时与其相同的帧不再重复打印
+func (s *stack) format(st fmt.State, verb rune, inner *stack) {
+	if s == nil {
+		return
+	}
 	switch verb {
 	case 'v':
 		switch {
 		case st.Flag('+'):
-			for _, pc := range *s {
+			frames := *s
+			shared := 0
+			if inner != nil && loadStackOptions().Collapse {
+				n := commonSuffix(frames, *inner)
+				for _, pc := range frames[len(frames)-n:] { //只统计会被打印的帧
+					if !Frame(pc).hidden() {
+						shared++
+					}
+				}
+				frames = frames[:len(frames)-n]
+			}
+			for _, pc := range frames {
 				f := Frame(pc)
+				if f.hidden() {
+					continue
+				}
 				fmt.Fprintf(st, "\n%+v", f)
 			}
+			if shared > 0 {
+				fmt.Fprintf(st, "\n\t... %d frames shared with the wrapped error", shared)
+			}
+		}
+	}
+}
+
+// commonSuffix 返回两个堆栈末尾(即调用链的上层)相同帧的数量
+func commonSuffix(a, b stack) int {
+	n := 0
+	for i, j := len(a)-1, len(b)-1; i >= 0 && j >= 0 && a[i] == b[j]; i, j = i-1, j-1 {
+		n++
+	}
+	return n
+}
+
+// innerStack 沿错误链找到最近的一个携带堆栈的错误,返回其堆栈
+func innerStack(err error) *stack {
+	for err != nil {
+		switch e := err.(type) {
+		case *baseError:
+			return e.stack
+		case *withStack:
+			return e.stack
+		case *withCode:
+			return e.stack
 		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil
+		}
+		err = u.Unwrap()
 	}
+	return nil
 }
 
 func (s *stack) StackTrace() StackTrace {
+	if s == nil {
+		return nil
+	}
 	f := make([]Frame, len(*s))
 	for i := 0; i < len(f); i++ {
 		f[i] = Frame((*s)[i]) //将*stack中的每一个堆栈转换为帧
 	}
 	return f
 }
+
+// textFrames 是以文本形式保存的堆栈,每一帧为Frame.MarshalText的结果,用于还原来自其他进程的错误
+type textFrames []string
+
+func (t textFrames) Format(st fmt.State, verb rune) {
+	if verb != 'v' || !st.Flag('+') {
+		return
+	}
+	opts := loadStackOptions()
+	for _, text := range t {
+		name, file := splitTextFrame(text)
+		if opts.SkipRuntime && (strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "testing.")) {
+			continue
+		}
+		fmt.Fprintf(st, "\n%s\n\t%s", name, trimFile(file))
+	}
+}
+
+// caller 将最近的一帧转换为 file:line (name) 的形式
+func (t textFrames) caller() string {
+	if len(t) == 0 {
+		return ""
+	}
+	name, file := splitTextFrame(t[0])
+	return fmt.Sprintf("%s (%s)", trimFile(file), name)
+}
+
+// splitTextFrame 将 "name file:line" 拆分为函数名和文件位置
+func splitTextFrame(text string) (name, file string) {
+	name, file, _ = strings.Cut(text, " ")
+	return name, file
+}
@@ -0,0 +1,84 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+//对zap的支持,使带错误码的错误能够以结构化字段的形式记录到日志中
+
+// MarshalLogObject 实现zapcore.ObjectMarshaler,输出code,message,error,caller以及整条错误链chain
+func (w *withCode) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalLogObject(w, enc, false)
+}
+
+type logObject struct {
+	err       error
+	withStack bool
+}
+
+func (o logObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return marshalLogObject(o.err, enc, o.withStack)
+}
+
+// LogObject 将任意错误转换为zapcore.ObjectMarshaler,withStack为true时额外输出最外层错误的完整堆栈,example:
+//
+//	logger.Error("create user failed", zap.Object("error", errors.LogObject(err, true)))
+func LogObject(err error, withStack bool) zapcore.ObjectMarshaler {
+	return logObject{err: err, withStack: withStack}
+}
+
+func marshalLogObject(err error, enc zapcore.ObjectEncoder, withStack bool) error {
+	if err == nil {
+		return nil
+	}
+	finfo := buildFormatInfo(err)
+	enc.AddInt("code", finfo.code)
+	enc.AddString("message", finfo.message)
+	enc.AddString("error", finfo.err)
+	if caller := finfo.caller(); caller != "" {
+		enc.AddString("caller", caller)
+	}
+
+	errs := list(err)
+	if len(errs) > 1 {
+		if err := enc.AddArray("chain", chainArray(errs)); err != nil {
+			return err
+		}
+	}
+	if withStack {
+		var stack string
+		if finfo.stack != nil {
+			stack = fmt.Sprintf("%+v", finfo.stack.StackTrace())
+		} else {
+			stack = fmt.Sprintf("%+v", finfo.frames)
+		}
+		if stack = strings.TrimLeft(stack, "\n"); stack != "" {
+			enc.AddString("stack", stack)
+		}
+	}
+	return nil
+}
+
+//chainArray 将错误链上的每一个错误编码为一个对象
+type chainArray []error
+
+func (c chainArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, e := range c {
+		finfo := buildFormatInfo(e)
+		err := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddInt("code", finfo.code)
+			enc.AddString("error", finfo.err)
+			if caller := finfo.caller(); caller != "" {
+				enc.AddString("caller", caller)
+			}
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//根据Options创建logger,New,Init以及Options.Build都通过这里创建
+
+const (
+	defaultTimeFormat = "2006-01-02 15:04:05.000"
+	omitKey           = "-" //EncoderKeys中设置为"-"时不输出该字段
+)
+
+// EncoderKeys 日志中各个字段的key,为空时使用默认值,为"-"时不输出该字段
+type EncoderKeys struct {
+	Message    string `json:"message"    mapstructure:"message"`
+	Level      string `json:"level"      mapstructure:"level"`
+	Time       string `json:"time"       mapstructure:"time"`
+	Name       string `json:"name"       mapstructure:"name"`
+	Caller     string `json:"caller"     mapstructure:"caller"`
+	Stacktrace string `json:"stacktrace" mapstructure:"stacktrace"`
+}
+
+func keyOr(key, def string) string {
+	switch key {
+	case "":
+		return def
+	case omitKey:
+		return zapcore.OmitKey
+	}
+	return key
+}
+
+// BuildOption 决定Options.Build创建logger之后对全局状态的修改,按顺序生效
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	installStd     bool
+	replaceGlobals bool
+	redirectStdLog bool
+	zapOptions     []zap.Option
+}
+
+// InstallAsStd 将创建的logger作为包级别的默认logger,即log.Info等函数使用的logger,
+// 同时应用Options中进程全局的V和VModule
+func InstallAsStd() BuildOption {
+	return func(o *buildOptions) { o.installStd = true }
+}
+
+// NoGlobals 不替换zap.L(),zap.S()也不重定向标准库的log,用于创建不影响全局状态的logger
+func NoGlobals() BuildOption {
+	return func(o *buildOptions) {
+		o.replaceGlobals = false
+		o.redirectStdLog = false
+	}
+}
+
+// ReplaceZapGlobals 将创建的logger设置为zap.L()和zap.S()
+func ReplaceZapGlobals() BuildOption {
+	return func(o *buildOptions) { o.replaceGlobals = true }
+}
+
+// RedirectStdLog 将标准库log的输出重定向到创建的logger,以Info级别记录
+func RedirectStdLog() BuildOption {
+	return func(o *buildOptions) { o.redirectStdLog = true }
+}
+
+// WithZapOptions 创建logger时附加的zap选项,如zap.Hooks,zap.Fields
+func WithZapOptions(opts ...zap.Option) BuildOption {
+	return func(o *buildOptions) { o.zapOptions = append(o.zapOptions, opts...) }
+}
+
+// Build 根据Options创建logger,与之前的版本一样默认替换zap.L(),zap.S()并重定向标准库的log,
+// 不需要时使用NoGlobals,example:
+//
+//	logger, err := opts.Build(log.InstallAsStd())
+//	logger, err := opts.Build(log.NoGlobals())
+//
+// 注意: 返回值由error改为了(Logger, error),原来的 err := opts.Build() 需要改为 _, err := opts.Build()
+func (o *Options) Build(opts ...BuildOption) (Logger, error) {
+	l, err := o.build(append([]BuildOption{ReplaceZapGlobals(), RedirectStdLog()}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (o *Options) build(opts ...BuildOption) (*zapLogger, error) {
+	var bo buildOptions
+	for _, opt := range opts {
+		opt(&bo)
+	}
+
+	var zapLevel zapcore.Level //将字符串,如 "debug","warn"等转换为整数level,有错误默认InfoLevel
+	if err := zapLevel.UnmarshalText([]byte(o.Level)); err != nil {
+		zapLevel = zapcore.InfoLevel
+	}
+	overrides, err := parseLevels(o.Levels)
+	if err != nil {
+		return nil, err
+	}
+	levels := newNameLevels(zap.NewAtomicLevelAt(zapLevel), o.Name, overrides)
+	vmodule, err := parseVModule(o.VModule)
+	if err != nil {
+		return nil, err
+	}
+
+	errSink, closeErr, err := o.openSinks(o.ErrorOutputPaths)
+	if err != nil {
+		return nil, err
+	}
+	core, err := o.buildCore(levels)
+	if err != nil {
+		closeErr()
+		return nil, err
+	}
+	core = o.Sampling.wrap(core)
+	core = &levelCore{Core: core, levels: levels}
+
+	zapOpts := []zap.Option{zap.ErrorOutput(errSink)}
+	if o.Development {
+		zapOpts = append(zapOpts, zap.Development())
+	}
+	if !o.DisableCaller {
+		zapOpts = append(zapOpts, zap.AddCaller())
+	}
+	if !o.DisableStacktrace {
+		zapOpts = append(zapOpts, zap.AddStacktrace(zapcore.PanicLevel)) //只在panicLevel打印堆栈
+	}
+	zapOpts = append(zapOpts, bo.zapOptions...)
+	base := zap.New(core, zapOpts...).Named(o.Name)
+
+	//zapLogger的方法和包级别的函数都多了一层调用
+	l := &zapLogger{levels: levels}
+	l.zapLogger = base.WithOptions(zap.AddCallerSkip(1))
+	l.infoLogger = infoLogger{level: zap.InfoLevel, log: l.zapLogger}
+
+	if bo.redirectStdLog {
+		zap.RedirectStdLog(base)
+	}
+	if bo.replaceGlobals {
+		zap.ReplaceGlobals(base)
+	}
+	if bo.installStd {
+		//与klog一致,V级别是进程全局的,只由默认的logger的配置决定
+		SetVerbosity(o.V)
+		vmodules.Store(vmodule)
+		setStd(l)
+	}
+	return l, nil
+}
+
+func (o *Options) encoder(format string, color bool) (zapcore.Encoder, error) {
+	switch strings.ToLower(format) {
+	case consoleFormat:
+		return zapcore.NewConsoleEncoder(o.encoderConfig(color)), nil
+	case jsonFormat:
+		return zapcore.NewJSONEncoder(o.encoderConfig(false)), nil
+	}
+	return nil, fmt.Errorf("not a valid log format: %q", format)
+}
+
+func (o *Options) encoderConfig(color bool) zapcore.EncoderConfig {
+	encodeLevel := zapcore.CapitalLevelEncoder
+	if color { //输出到文件时不应开启颜色
+		encodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	return zapcore.EncoderConfig{
+		MessageKey:     keyOr(o.Keys.Message, "message"),
+		LevelKey:       keyOr(o.Keys.Level, "level"),
+		TimeKey:        keyOr(o.Keys.Time, "timestamp"),
+		NameKey:        keyOr(o.Keys.Name, "logger"),
+		CallerKey:      keyOr(o.Keys.Caller, "caller"),
+		StacktraceKey:  keyOr(o.Keys.Stacktrace, "stacktrace"),
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    vLevelEncoder(encodeLevel),
+		EncodeTime:     timeEncoderOf(o.TimeFormat),
+		EncodeDuration: milliSecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeName:     zapcore.FullNameEncoder,
+	}
+}
+
+//timeEncoderOf 支持zap预定义的rfc3339,rfc3339nano,iso8601,millis,nanos,epoch,其他值作为time.Format的layout
+func timeEncoderOf(format string) zapcore.TimeEncoder {
+	switch strings.ToLower(format) {
+	case "":
+		return timeEncoder
+	case "rfc3339", "rfc3339nano", "iso8601", "millis", "nanos", "epoch":
+		var enc zapcore.TimeEncoder
+		_ = enc.UnmarshalText([]byte(strings.ToLower(format)))
+		return enc
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.Format(format))
+	}
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"encoding/json"
+	stdlog "log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leilei3167/basic/pkg/errors"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+func TestBuild(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	opts := NewOptions()
+	opts.Format = jsonFormat
+	opts.OutputPaths = []string{filename}
+	opts.TimeFormat = "rfc3339"
+	opts.Keys = EncoderKeys{Time: "ts", Message: "msg", Name: "-"}
+	opts.Name = "build"
+
+	l, err := opts.Build(InstallAsStd(), NoGlobals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := std
+	defer setStd(prev)
+	if std != l {
+		t.Fatal("InstallAsStd should replace the default logger")
+	}
+
+	l.Info("from method")
+	Info("from func")
+	l.Flush()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %q", data)
+	}
+	for i, msg := range []string{"from method", "from func"} {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry["msg"] != msg || entry["logger"] != nil {
+			t.Errorf("unexpected entry %v", entry)
+		}
+		if _, err := time.Parse(time.RFC3339, entry["ts"].(string)); err != nil {
+			t.Errorf("ts: %v", err)
+		}
+		if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "log/build_test.go:") {
+			t.Errorf("caller should point to the test, got %q", caller)
+		}
+	}
+
+	opts.Format = "xml"
+	if _, err := opts.Build(); err == nil {
+		t.Error("want error for invalid format")
+	}
+}
+
+func TestBuildGlobals(t *testing.T) {
+	prevZap := zap.L()
+	defer zap.ReplaceGlobals(prevZap)
+	defer stdlog.SetOutput(stdlog.Writer())
+	defer stdlog.SetFlags(stdlog.Flags())
+	defer stdlog.SetPrefix(stdlog.Prefix())
+
+	filename := filepath.Join(t.TempDir(), "globals.log")
+	opts := NewOptions()
+	opts.OutputPaths = []string{filename}
+	if _, err := opts.Build(NoGlobals()); err != nil {
+		t.Fatal(err)
+	}
+	if zap.L() != prevZap {
+		t.Error("NoGlobals should keep zap.L()")
+	}
+
+	l, err := opts.Build() //默认与之前的版本一致
+	if err != nil {
+		t.Fatal(err)
+	}
+	zap.L().Info("from zap.L")
+	stdlog.Print("from std log")
+	l.Flush()
+	data, _ := os.ReadFile(filename)
+	if !strings.Contains(string(data), "from zap.L") || !strings.Contains(string(data), "from std log") {
+		t.Errorf("Build should replace zap globals and redirect std log, got %q", data)
+	}
+}
+
+//tcp和udp不占用zap的全局scheme,其他包依然可以注册,测试中只能注册一次
+var errRegisterUDP = zap.RegisterSink("udp", func(*url.URL) (zap.Sink, error) { return nil, nil })
+
+func TestSinks(t *testing.T) {
+	dir := t.TempDir()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	opts := NewOptions()
+	opts.Level = "debug"
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts.AddFlags(fs)
+	err = fs.Parse([]string{
+		"--log.sinks=" + filepath.Join(dir, "info.log") + ",level=info,format=console",
+		"--log.sinks=" + filepath.Join(dir, "debug.json") + ",format=json",
+		"--log.sinks=udp://" + pc.LocalAddr().String() + ",level=warn,format=json",
+		"--log.sinks=" + filepath.Join(dir, `a\,b.log`) + ",level=error",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := opts.Validate(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	l, err := opts.Build(NoGlobals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("debug message")
+	l.Warn("warn message")
+	l.Error("error message")
+	l.Flush()
+
+	info, _ := os.ReadFile(filepath.Join(dir, "info.log"))
+	if strings.Contains(string(info), "debug message") || !strings.Contains(string(info), "\twarn message") {
+		t.Errorf("unexpected console sink output %q", info)
+	}
+	debug, _ := os.ReadFile(filepath.Join(dir, "debug.json"))
+	if !strings.Contains(string(debug), `"message":"debug message"`) || !strings.Contains(string(debug), `"message":"warn message"`) {
+		t.Errorf("unexpected json sink output %q", debug)
+	}
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil || !strings.Contains(string(buf[:n]), `"message":"warn message"`) {
+		t.Errorf("unexpected udp sink output %q %v", buf[:n], err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "a,b.log")); !strings.Contains(string(data), "error message") {
+		t.Errorf("escaped comma in sink path: got %q", data)
+	}
+	if errRegisterUDP != nil {
+		t.Errorf("udp scheme should be left to other packages: %v", errRegisterUDP)
+	}
+
+	if err := fs.Parse([]string{"--log.sinks=stdout,format=xml"}); err == nil {
+		t.Error("want error for invalid sink format")
+	}
+}
+
+func TestErrorFieldStack(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "error.log")
+	opts := NewOptions()
+	opts.Format = jsonFormat
+	opts.OutputPaths = []string{filename}
+	opts.Level = "debug"
+	opts.Levels = map[string]string{"quiet": "info"}
+	l, err := opts.Build(NoGlobals()) //默认的logger依然是info级别
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = errors.WithCode(1, "db timeout")
+	l.Error("debug logger", ErrorField(err))
+	l.WithName("quiet").Error("info logger", ErrorField(err))
+	zl := l.(*zapLogger)
+	zl.zapLogger.With(ErrorField(err)).Error("with fields")
+
+	//是否记录堆栈由写入时的级别决定,而不是With时的级别
+	debugWith := zl.zapLogger.With(ErrorField(err))
+	zl.levels.base.SetLevel(InfoLevel)
+	infoWith := zl.zapLogger.With(ErrorField(err))
+	debugWith.Error("with fields after SetLevel(info)")
+	zl.levels.base.SetLevel(DebugLevel)
+	infoWith.Error("with fields after SetLevel(debug)")
+	l.Flush()
+
+	data, _ := os.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("want 5 lines, got %q", data)
+	}
+	for i, want := range []bool{true, false, true, false, true} {
+		var entry struct {
+			Error map[string]any
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := entry.Error["stack"]; ok != want {
+			t.Errorf("line %d: stack recorded %v, want %v: %s", i, ok, want, lines[i])
+		}
+	}
+}
+
+func TestBuildNamedLevelsWithName(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	opts := NewOptions()
+	opts.Format = jsonFormat
+	opts.OutputPaths = []string{filename}
+	opts.Name = "app"
+	opts.Levels = map[string]string{"db": "debug", "http": "error"}
+	l, err := opts.Build(NoGlobals())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.WithName("db").WithName("pool").Debug("db.pool debug") //app.db.pool 匹配 db
+	l.WithName("http").Warn("http warn")
+	l.Debug("root debug")
+	l.Info("root info")
+	l.Flush()
+
+	data, _ := os.ReadFile(filename)
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry struct{ Logger, Message string }
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, entry.Logger+":"+entry.Message)
+	}
+	want := []string{"app.db.pool:db.pool debug", "app:root info"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -1,11 +1,20 @@
 package log
 
-import "context"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
 
 type key int
 
 const (
 	logContextKey key = iota
+	requestIDKey
+	usernameKey
+	traceIDKey
+	spanIDKey
+	fieldsKey
 )
 
 func WithContext(ctx context.Context) context.Context {
@@ -20,13 +29,156 @@ func (l *zapLogger) WithContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, logContextKey, l)
 }
 
-// FromContext 从ctx取出logger,如果没有logger,则创建一个Unknown-Context
+// FromContext 从ctx取出logger,如果没有logger,则创建一个Unknown-Context,并附加上ContextExtractor从ctx中提取的字段,
+// 放入ctx的logger如果是通过L(ctx)创建的,已经添加过的字段不会重复添加
 func FromContext(ctx context.Context) Logger {
-	if ctx != nil {
-		logger := ctx.Value(logContextKey)
-		if logger != nil {
-			return logger.(Logger)
+	if ctx == nil {
+		return WithName("Unknown-Context")
+	}
+	var logger Logger
+	if l, ok := ctx.Value(logContextKey).(Logger); ok {
+		logger = l
+	} else {
+		logger = WithName("Unknown-Context")
+	}
+	if zl, ok := logger.(*zapLogger); ok {
+		return zl.L(ctx)
+	}
+	fields := extractFields(ctx)
+	if len(fields) == 0 {
+		return logger
+	}
+	//其他实现的WithValues只接受key-value,不能直接传入zap的Field
+	args := make([]any, 0, 2*len(fields))
+	for _, f := range fields {
+		encodeField(f, func(k string, v any) { args = append(args, k, v) })
+	}
+	return logger.WithValues(args...)
+}
+
+//以下提供类型安全的ctx设置方法,避免与其他包使用相同的字符串key冲突
+
+// ContextWithRequestID 将requestID放入ctx,L(ctx)和FromContext会将其记录在requestID字段
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// ContextWithUsername 将用户名放入ctx,记录在username字段
+func ContextWithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// ContextWithTrace 将链路追踪的traceID和spanID放入ctx,记录在traceID和spanID字段
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	ctx = context.WithValue(ctx, traceIDKey, traceID)
+	return context.WithValue(ctx, spanIDKey, spanID)
+}
+
+// ContextWithFields 将任意字段放入ctx,与ctx中已有的字段合并
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	prev, _ := ctx.Value(fieldsKey).([]Field)
+	merged := make([]Field, 0, len(prev)+len(fields))
+	merged = append(append(merged, prev...), fields...)
+	return context.WithValue(ctx, fieldsKey, merged)
+}
+
+// RequestIDFromContext 返回ContextWithRequestID放入的requestID,兼容使用KeyRequestID字符串key放入的值
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	v, ok := valueOf(ctx, requestIDKey, KeyRequestID).(string)
+	return v, ok
+}
+
+//valueOf 优先使用类型安全的key,其次使用字符串key(如gin.Context.Set设置的值)
+func valueOf(ctx context.Context, typed key, fallback string) any {
+	if v := ctx.Value(typed); v != nil {
+		return v
+	}
+	return ctx.Value(fallback)
+}
+
+// ContextExtractor 从ctx中提取需要记录的字段,如租户,客户端IP等,没有时返回nil
+type ContextExtractor func(ctx context.Context) []Field
+
+type extractorEntry struct {
+	id int //函数不能比较,删除时按id查找
+	fn ContextExtractor
+}
+
+var (
+	extractorMu sync.Mutex
+	extractors  atomic.Pointer[[]extractorEntry]
+	extractorID int
+)
+
+func init() {
+	AddContextExtractor(defaultExtractor)
+}
+
+// AddContextExtractor 添加一个ContextExtractor,L(ctx)和FromContext会依次调用,返回的函数用于删除该extractor,example:
+//
+//	log.AddContextExtractor(func(ctx context.Context) []log.Field {
+//		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+//			return []log.Field{log.String("tenant", tenant)}
+//		}
+//		return nil
+//	})
+func AddContextExtractor(fn ContextExtractor) (remove func()) {
+	extractorMu.Lock()
+	defer extractorMu.Unlock()
+
+	extractorID++
+	id := extractorID
+	var es []extractorEntry
+	if prev := extractors.Load(); prev != nil {
+		es = append(es, *prev...)
+	}
+	es = append(es, extractorEntry{id: id, fn: fn})
+	extractors.Store(&es)
+
+	return func() {
+		extractorMu.Lock()
+		defer extractorMu.Unlock()
+
+		var es []extractorEntry
+		for _, e := range *extractors.Load() {
+			if e.id != id {
+				es = append(es, e)
+			}
+		}
+		extractors.Store(&es)
+	}
+}
+
+//defaultExtractor 提取requestID,username,traceID,spanID以及ContextWithFields放入的字段
+func defaultExtractor(ctx context.Context) []Field {
+	var fields []Field
+	for _, k := range []struct {
+		typed key
+		name  string
+	}{
+		{requestIDKey, KeyRequestID},
+		{usernameKey, KeyUsername},
+		{traceIDKey, KeyTraceID},
+		{spanIDKey, KeySpanID},
+	} {
+		if v := valueOf(ctx, k.typed, k.name); v != nil {
+			fields = append(fields, Any(k.name, v))
 		}
 	}
-	return WithName("Unknown-Context")
+	if fs, ok := ctx.Value(fieldsKey).([]Field); ok {
+		fields = append(fields, fs...)
+	}
+	return fields
+}
+
+func extractFields(ctx context.Context) []Field {
+	es := extractors.Load()
+	if es == nil {
+		return nil
+	}
+	var fields []Field
+	for _, e := range *es {
+		fields = append(fields, e.fn(ctx)...)
+	}
+	return fields
 }
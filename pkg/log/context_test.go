@@ -0,0 +1,105 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type tenantKey struct{}
+
+func TestContextFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	zl := zap.New(core)
+	l := &zapLogger{zapLogger: zl, infoLogger: infoLogger{log: zl, level: InfoLevel}}
+
+	remove := AddContextExtractor(func(ctx context.Context) []Field {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []Field{String("tenant", tenant)}
+		}
+		return nil
+	})
+	defer remove()
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	ctx = ContextWithTrace(ctx, "trace-1", "span-1")
+	ctx = context.WithValue(ctx, KeyUsername, "colin") //兼容字符串key
+	ctx = ContextWithFields(ctx, String("clientIP", "10.0.0.1"))
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	l.L(ctx).Info("from L")
+	FromContext(l.WithContext(ctx)).Info("from FromContext")
+	FromContext(l.L(ctx).WithContext(ctx)).Info("from FromContext after L") //字段不重复
+
+	want := map[string]any{
+		KeyRequestID: "req-1", KeyTraceID: "trace-1", KeySpanID: "span-1",
+		KeyUsername: "colin", "clientIP": "10.0.0.1", "tenant": "acme",
+	}
+	for _, e := range logs.All() {
+		got := e.ContextMap()
+		if len(got) != len(want) {
+			t.Errorf("%s: got %v, want %v", e.Message, got, want)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", e.Message, k, got[k], v)
+			}
+		}
+	}
+	if n := logs.Len(); n != 3 {
+		t.Errorf("want 3 entries, got %d", n)
+	}
+	for _, e := range logs.All() {
+		if len(e.Context) != len(want) {
+			t.Errorf("%s: duplicated fields %v", e.Message, e.Context)
+		}
+	}
+	if id, ok := RequestIDFromContext(ctx); !ok || id != "req-1" {
+		t.Errorf("RequestIDFromContext: got %q %v", id, ok)
+	}
+
+	remove()
+	l.L(ctx).Info("after remove")
+	if _, ok := logs.All()[3].ContextMap()["tenant"]; ok {
+		t.Error("removed extractor should not be called")
+	}
+
+	//嵌套请求的requestID发生变化时使用新的值
+	boot := l.L(ContextWithRequestID(context.Background(), "boot"))
+	req := boot.L(ContextWithRequestID(context.Background(), "req-2"))
+	req.Info("nested")
+	req.L(ContextWithRequestID(context.Background(), "boot")).Info("back to boot")
+	for i, want := range []string{"req-2", "boot"} {
+		if got := logs.All()[4+i].ContextMap()[KeyRequestID]; got != want {
+			t.Errorf("%s: requestID = %v, want %s", logs.All()[4+i].Message, got, want)
+		}
+	}
+}
+
+//valuesLogger 记录WithValues的参数,用于检查非zap实现的Logger
+type valuesLogger struct {
+	Logger
+	values []any
+}
+
+func (l *valuesLogger) WithValues(keysAndValues ...any) Logger {
+	l.values = append(l.values, keysAndValues...)
+	return l
+}
+
+func TestFromContextValues(t *testing.T) {
+	vl := &valuesLogger{}
+	ctx := context.WithValue(context.Background(), logContextKey, Logger(vl))
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = ContextWithFields(ctx, Int("attempt", 2))
+
+	FromContext(ctx)
+	want := []any{KeyRequestID, "req-1", "attempt", int64(2)}
+	if !reflect.DeepEqual(vl.values, want) {
+		t.Errorf("WithValues: got %#v, want %#v", vl.values, want)
+	}
+}
@@ -6,8 +6,9 @@ package main
 
 import (
 	"context"
+	"github.com/leilei3167/basic/pkg/errors"
 	"github.com/spf13/pflag"
-	"net-mapping/pkg/log"
+	"github.com/leilei3167/basic/pkg/log"
 )
 
 func main() {
@@ -34,6 +35,9 @@ func main() {
 	log.Warnf("This is a formatted %s message", "warn")
 	log.Errorw("Message printed with Errorw", "X-Request-ID", "fbf54504-64da-4088-9b86-67824a7fb508")
 
+	// 带错误码的错误以结构化字段记录
+	log.Error("This is a error message with structured error", log.ErrorField(errors.WithCode(1, "db timeout")))
+
 	// WithValues使用,使用指定的k-v创建logger,之后通过它打印的日志都会带有该kv
 	lv := log.WithValues("X-Request-ID", "7a7b9f24-4cae-4b2a-9464-69088b45b904")
 	lv.Infow("Info message printed with [WithValues] logger")
@@ -44,12 +48,17 @@ func main() {
 	lc := log.FromContext(ctx)                  //从ctx中取出携带有k-v的logger
 	lc.Info("Message printed with [WithContext] logger")
 
+	// 类型安全的ctx设置方法,L(ctx)会提取其中的requestID,traceID等字段
+	rctx := log.ContextWithRequestID(context.Background(), "fbf54504-64da-4088-9b86-67824a7fb508")
+	rctx = log.ContextWithTrace(rctx, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	log.L(rctx).Info("Message printed with [L] logger")
+
 	//再lv之中新建一个带有名称的子logger
 	ln := lv.WithName("test")
 	ln.Info("Message printed with [WithName] logger")
 
 	// V level使用
-	log.V(log.InfoLevel).Info("This is a V level message")
-	log.V(log.ErrorLevel).
+	log.V(0).Info("This is a V level message")
+	log.V(2).
 		Infow("This is a V level message with fields", "X-Request-ID", "7a7b9f24-4cae-4b2a-9464-69088b45b904")
 }
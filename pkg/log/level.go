@@ -0,0 +1,113 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap/zapcore"
+)
+
+//运行时修改日志级别,New创建的logger及其派生的logger共享同一个zap.AtomicLevel
+
+// SetLevel 修改默认logger的全局日志级别,不影响通过SetNamedLevel覆盖的logger
+func SetLevel(level Level) {
+	if std.levels != nil {
+		std.levels.base.SetLevel(level)
+	}
+}
+
+// GetLevel 返回默认logger当前的全局日志级别
+func GetLevel() Level {
+	if std.levels != nil {
+		return std.levels.base.Level()
+	}
+	return levelOf(std.zapLogger.Core())
+}
+
+//levelOf 返回core开启的最低日志级别
+func levelOf(core zapcore.Core) Level {
+	for lvl := zapcore.DebugLevel; lvl < zapcore.FatalLevel; lvl++ {
+		if core.Enabled(lvl) {
+			return lvl
+		}
+	}
+	return zapcore.FatalLevel
+}
+
+//stepLevel 将日志级别调整delta级,限制在Debug和Fatal之间
+func stepLevel(delta int) Level {
+	lvl := GetLevel() + Level(delta)
+	if lvl < zapcore.DebugLevel {
+		lvl = zapcore.DebugLevel
+	}
+	if lvl > zapcore.FatalLevel {
+		lvl = zapcore.FatalLevel
+	}
+	SetLevel(lvl)
+	return lvl
+}
+
+type levelPayload struct {
+	Logger    string   `json:"logger,omitempty"`
+	Level     string   `json:"level"`
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// LevelHandler 返回查询和修改日志级别的http.Handler,查询参数logger指定按名称覆盖的日志级别,example:
+//
+//	http.Handle("/log/level", log.LevelHandler())
+//
+//	curl localhost:8080/log/level
+//	curl -X PUT localhost:8080/log/level -d '{"level":"debug"}'
+//	curl -X PUT localhost:8080/log/level?logger=db -d '{"level":"debug"}'
+//	curl -X DELETE localhost:8080/log/level?logger=db
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("logger")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			if name == "" {
+				writeLevelError(w, http.StatusBadRequest, "logger is required")
+				return
+			}
+			UnsetNamedLevel(name)
+			Info("log level override removed", String("logger", name))
+		case http.MethodPut:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+			var lvl zapcore.Level
+			if err := lvl.UnmarshalText([]byte(req.Level)); err != nil {
+				writeLevelError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if name == "" {
+				SetLevel(lvl)
+			} else {
+				SetNamedLevel(name, lvl)
+			}
+			Info("log level changed", String("logger", name), String("level", lvl.String()))
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			writeLevelError(w, http.StatusMethodNotAllowed, "only GET, PUT and DELETE are supported")
+			return
+		}
+
+		resp := levelPayload{Logger: name, Level: GetNamedLevel(name).String()}
+		if name == "" && std.levels != nil {
+			resp.Overrides = std.levels.overrides()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func writeLevelError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevelHandler(t *testing.T) {
+	defer SetLevel(InfoLevel)
+
+	h := LevelHandler()
+	do := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+		return w
+	}
+	doNamed := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/log/level?logger=db", strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodGet, ""); strings.TrimSpace(w.Body.String()) != `{"level":"info"}` {
+		t.Errorf("GET: got %s", w.Body.String())
+	}
+	if w := do(http.MethodPut, `{"level":"debug"}`); w.Code != http.StatusOK || GetLevel() != DebugLevel {
+		t.Errorf("PUT: got %d %s, level %v", w.Code, w.Body.String(), GetLevel())
+	}
+	if !WithName("derived").(*zapLogger).zapLogger.Core().Enabled(DebugLevel) {
+		t.Error("derived logger should share the level")
+	}
+	if w := do(http.MethodPut, `{"level":"verbose"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid level: got %d", w.Code)
+	}
+	if w := doNamed(http.MethodPut, `{"level":"error"}`); strings.TrimSpace(w.Body.String()) != `{"logger":"db","level":"error"}` {
+		t.Errorf("PUT logger: got %s", w.Body.String())
+	}
+	if GetNamedLevel("db.pool") != ErrorLevel || GetNamedLevel("http") != DebugLevel {
+		t.Errorf("unexpected named levels: %v %v", GetNamedLevel("db.pool"), GetNamedLevel("http"))
+	}
+	if w := doNamed(http.MethodDelete, ""); strings.TrimSpace(w.Body.String()) != `{"logger":"db","level":"debug"}` {
+		t.Errorf("DELETE logger: got %s", w.Body.String())
+	}
+	if w := do(http.MethodPost, ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got %d", w.Code)
+	}
+
+	if lvl := stepLevel(-1); lvl != DebugLevel {
+		t.Errorf("step below debug: got %v", lvl)
+	}
+	if lvl := stepLevel(2); lvl != WarnLevel {
+		t.Errorf("step up: got %v", lvl)
+	}
+}
+
+func TestNamedLevel(t *testing.T) {
+	levels := newNameLevels(zap.NewAtomicLevelAt(InfoLevel), "", map[string]zapcore.Level{
+		"db":          DebugLevel,
+		"http.access": WarnLevel,
+	})
+	core, logs := observer.New(zapcore.Level(-10))
+	l := zap.New(&levelCore{Core: core, levels: levels})
+
+	l.Named("db").Named("pool").Debug("db.pool debug")
+	l.Named("dbx").Debug("dbx debug")
+	l.Named("http").Info("http info")
+	l.Named("http").Named("access").Info("http.access info")
+	l.Named("http").Named("access").Warn("http.access warn")
+	l.Debug("root debug")
+
+	levels.set("http", ErrorLevel)
+	l.Named("http").Warn("http warn")
+	levels.unset("db")
+	l.Named("db").Debug("db debug after unset")
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"db.pool debug", "http info", "http.access warn"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if l.Core().Enabled(DebugLevel) || !l.Core().Enabled(WarnLevel) {
+		t.Error("Enabled should report whether any logger may log at the level")
+	}
+}
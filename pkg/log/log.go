@@ -6,6 +6,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"log"
+	"slices"
 	"sync"
 )
 
@@ -44,7 +45,8 @@ type Logger interface {
 	Fatalf(format string, v ...any)
 	Fatalw(msg string, keysAndValues ...any)
 
-	V(level Level) InfoLogger
+	// V 返回klog风格的整数级别的InfoLogger,数值越大越详细,由--log.v和--log.vmodule控制是否开启
+	V(level int) InfoLogger
 	Write(p []byte) (n int, err error)
 
 	WithValues(keysAndValues ...any) Logger
@@ -82,7 +84,7 @@ func (l *infoLogger) Info(msg string, fields ...Field) {
 }
 
 func (l *infoLogger) Infof(fomat string, args ...any) {
-	if checkedEntry := l.log.Check(l.level, fmt.Sprintf(fomat, args)); checkedEntry != nil {
+	if checkedEntry := l.log.Check(l.level, fmt.Sprintf(fomat, args...)); checkedEntry != nil {
 		checkedEntry.Write()
 	}
 }
@@ -132,14 +134,44 @@ func handleFields(l *zap.Logger, args []any, additional ...zap.Field) []zap.Fiel
 
 var (
 	mu  sync.Mutex
-	std = New(NewOptions()) //默认的logger
+	std *zapLogger //默认的logger
 )
 
+func init() {
+	std = New(NewOptions())
+}
+
 // Init 使用特定的options来修改默认的logger
 func Init(opts *Options) {
+	if opts == nil {
+		opts = NewOptions()
+	}
+	if _, err := opts.build(RedirectStdLog(), InstallAsStd()); err != nil {
+		panic(err)
+	}
+}
+
+//setStd 替换默认的logger,返回原来的logger
+func setStd(l *zapLogger) *zapLogger {
 	mu.Lock()
 	defer mu.Unlock()
-	std = New(opts)
+	prev := std
+	std = l
+	return prev
+}
+
+// ReplaceStd 将l设置为包级别的默认logger,返回恢复原来logger的函数,
+// l需要由本包的New,Build或NewLogger创建,常用于测试,example:
+//
+//	restore := log.ReplaceStd(logger)
+//	defer restore()
+func ReplaceStd(l Logger) (restore func()) {
+	zl, ok := l.(*zapLogger)
+	if !ok || zl == nil {
+		panic(fmt.Sprintf("log: ReplaceStd: unsupported logger %T", l))
+	}
+	prev := setStd(zl)
+	return func() { setStd(prev) }
 }
 
 // NewLogger 根据zap.logger构建一个实现Logger接口的实例
@@ -156,15 +188,9 @@ func ZapLogger() *zap.Logger {
 	return std.zapLogger
 }
 
+// CheckIntLevel 判断V(level)是否开启
 func CheckIntLevel(level int32) bool {
-	var lvl zapcore.Level
-	if level < 5 {
-		lvl = zapcore.InfoLevel
-	} else {
-		lvl = zapcore.DebugLevel
-	}
-	checkEntry := std.zapLogger.Check(lvl, "")
-	return checkEntry != nil
+	return std.v(int(level)).Enabled()
 }
 
 func SugaredLogger() *zap.SugaredLogger {
@@ -192,20 +218,30 @@ func StdInfoLogger() *log.Logger {
 //底层使用zap来记录日志
 type zapLogger struct {
 	zapLogger  *zap.Logger
-	infoLogger //继承infoLogger的打印Info的方法
+	infoLogger             //继承infoLogger的打印Info的方法
+	levels     *nameLevels //New创建的logger的日志级别,可以在运行时修改,NewLogger创建的logger为nil
+	ctxFields  []Field     //L(ctx)已经从ctx中提取过的字段,每个key只保留最新的值,相同的字段不再重复添加
 }
 
-// V 可以通过整型数值来快速指定日志级别打印日志，数值越大，日志级别越高
-func V(level Level) InfoLogger { return std.V(level) }
+// V 返回klog风格的整数级别的InfoLogger,V(n)在n<=--log.v或者--log.vmodule中匹配的级别时开启,example:
+//
+//	log.V(2).Infow("sync pod", "pod", name)
+//	if log.V(4).Enabled() {...}
+func V(level int) InfoLogger { return std.v(level) }
 
-func (l *zapLogger) V(level Level) InfoLogger {
-	if l.zapLogger.Core().Enabled(level) {
-		return &infoLogger{
-			level: level,
-			log:   l.zapLogger,
-		}
+func (l *zapLogger) V(level int) InfoLogger { return l.v(level) }
+
+//v 需要由V直接调用,以便vmodule获取用户调用处的文件
+func (l *zapLogger) v(level int) InfoLogger {
+	level = clampV(level)
+	if !vEnabled(level, 2) {
+		return disabledInfoLogger
+	}
+	lvl := vLevel(level)
+	if !l.zapLogger.Core().Enabled(lvl) {
+		return disabledInfoLogger
 	}
-	return disabledInfoLogger
+	return &infoLogger{level: lvl, log: l.zapLogger}
 }
 
 func Debug(msg string, fields ...Field) { std.zapLogger.Debug(msg, fields...) }
@@ -335,14 +371,14 @@ func WithValues(keysAndValues ...any) Logger {
 //WithValues 可以返回一个携带指定 key-value 的 Logger，供后面使用。
 func (l *zapLogger) WithValues(keysAndValues ...any) Logger {
 	newLogger := l.zapLogger.With(handleFields(l.zapLogger, keysAndValues)...)
-	return NewLogger(newLogger)
+	return l.derive(newLogger)
 }
 
 func WithName(s string) Logger { return std.WithName(s) }
 
 func (l *zapLogger) WithName(name string) Logger {
 	newLogger := l.zapLogger.Named(name)
-	return NewLogger(newLogger)
+	return l.derive(newLogger)
 }
 
 func Flush() { std.Flush() }
@@ -355,17 +391,58 @@ func L(ctx context.Context) *zapLogger {
 	return std.L(ctx)
 }
 
-// L 方法会从传入的 Context 中提取出 requestID 和 username ，追加到 Logger 中，并返回 Logger
+// L 方法会通过ContextExtractor从传入的 Context 中提取出 requestID, username, traceID 等字段，追加到 Logger 中，并返回 Logger
 func (l *zapLogger) L(ctx context.Context) *zapLogger {
-	lg := l.clone()
-	if requestID := ctx.Value(KeyRequestID); requestID != nil {
-		lg.zapLogger = lg.zapLogger.With(zap.Any(KeyRequestID, requestID))
+	fields := l.missingFields(extractFields(ctx))
+	if len(fields) == 0 {
+		return l.clone()
 	}
-	if username := ctx.Value(KeyUsername); username != nil {
-		lg.zapLogger = lg.zapLogger.With(zap.Any(KeyUsername, username))
+	nl := l.derive(l.zapLogger.With(fields...))
+	nl.ctxFields = mergeFields(l.ctxFields, fields)
+	return nl
+}
+
+//missingFields 去掉已经通过L(ctx)添加到logger中的字段,只有key和值都相同时才跳过,
+//值发生变化的字段(如嵌套请求的requestID)需要重新添加
+func (l *zapLogger) missingFields(fields []Field) []Field {
+	return missingFields(l.ctxFields, fields)
+}
+
+func missingFields(added, fields []Field) []Field {
+	if len(added) == 0 {
+		return fields
+	}
+	ret := fields[:0:0]
+	for _, f := range fields {
+		i := slices.IndexFunc(added, func(a Field) bool { return a.Key == f.Key })
+		if i < 0 || !added[i].Equals(f) {
+			ret = append(ret, f)
+		}
 	}
+	return ret
+}
 
-	return lg
+//mergeFields 返回added中的字段被fields中同名的字段覆盖后的结果,不修改added
+func mergeFields(added, fields []Field) []Field {
+	ret := slices.Clone(added)
+	for _, f := range fields {
+		if i := slices.IndexFunc(ret, func(a Field) bool { return a.Key == f.Key }); i >= 0 {
+			ret[i] = f
+		} else {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+//derive 基于新的zap.Logger创建logger,保留日志级别和已经提取过的ctx字段
+func (l *zapLogger) derive(zl *zap.Logger) *zapLogger {
+	return &zapLogger{
+		zapLogger:  zl,
+		infoLogger: infoLogger{log: zl, level: zap.InfoLevel},
+		levels:     l.levels,
+		ctxFields:  l.ctxFields,
+	}
 }
 
 func (l *zapLogger) clone() *zapLogger {
@@ -375,66 +452,14 @@ func (l *zapLogger) clone() *zapLogger {
 
 var _ Logger = (*zapLogger)(nil)
 
-// New 根据opts创建一个logger
+// New 根据opts创建一个logger并重定向标准库的log,创建失败时panic,需要处理错误时使用Options.Build
 func New(opts *Options) *zapLogger {
 	if opts == nil {
 		opts = NewOptions()
 	}
-
-	var zapLevel zapcore.Level //将字符串,如 "debug","warn"等转换为整数level,有错误默认InfoLevel
-	if err := zapLevel.UnmarshalText([]byte(opts.Level)); err != nil {
-		zapLevel = zapcore.InfoLevel
-	}
-	encodeLevel := zapcore.CapitalLevelEncoder
-	// when output to local path, with color is forbidden
-	if opts.Format == consoleFormat && opts.EnableColor {
-		encodeLevel = zapcore.CapitalColorLevelEncoder
-	}
-
-	encoderConfig := zapcore.EncoderConfig{
-		MessageKey:     "message",
-		LevelKey:       "level",
-		TimeKey:        "timestamp",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    encodeLevel,
-		EncodeTime:     timeEncoder,
-		EncodeDuration: milliSecondsDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	loggerConfig := &zap.Config{
-		Level:             zap.NewAtomicLevelAt(zapLevel),
-		Development:       opts.Development,
-		DisableCaller:     opts.DisableCaller,
-		DisableStacktrace: opts.DisableStacktrace,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding:         opts.Format,
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      opts.OutputPaths,
-		ErrorOutputPaths: opts.ErrorOutputPaths,
-	}
-
-	//构建
-	var err error
-	l, err := loggerConfig.Build(zap.AddStacktrace(zapcore.PanicLevel), zap.AddCallerSkip(1))
+	l, err := opts.build(RedirectStdLog())
 	if err != nil {
 		panic(err)
 	}
-
-	logger := &zapLogger{
-		zapLogger: l.Named(opts.Name),
-		infoLogger: infoLogger{
-			level: zap.InfoLevel,
-			log:   l,
-		},
-	}
-
-	zap.RedirectStdLog(l)
-	return logger
+	return l
 }
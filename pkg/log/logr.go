@@ -0,0 +1,213 @@
+package log
+
+import (
+	"runtime"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//logr与Logger的相互转换,两个方向使用同一个映射:logr的V(0)对应Info,V(1)对应Debug,
+//V(n)对应本包的V(n-1),Error对应Error级别;本包的V(0)默认开启,与Info一样对应logr的V(0)
+
+// ToLogr 将Logger转换为logr.Logger,供controller-runtime等使用logr的库输出到相同的目的地,
+// l不是本包创建的Logger时使用默认的logger,example:
+//
+//	ctrl.SetLogger(log.ToLogr(log.WithName("controller")))
+func ToLogr(l Logger) logr.Logger {
+	return logr.New(&logrSink{zl: zapOf(l)})
+}
+
+//zapOf 返回Logger底层的zap.Logger,调用方需要与zapLogger的方法一样多一层调用
+func zapOf(l Logger) *zap.Logger {
+	if zl, ok := l.(*zapLogger); ok && zl != nil {
+		return zl.zapLogger
+	}
+	return std.zapLogger
+}
+
+//logrLevel 返回logr的V级别对应的zap级别,与logrV互逆
+func logrLevel(level int) zapcore.Level {
+	switch {
+	case level <= 0:
+		return zapcore.InfoLevel
+	case level == 1:
+		return zapcore.DebugLevel
+	}
+	return vLevel(clampV(level - 1))
+}
+
+//logrSink 实现logr.LogSink,depth为logr.Logger到用户代码之间的层数
+type logrSink struct {
+	zl    *zap.Logger
+	depth int
+}
+
+var _ logr.CallDepthLogSink = (*logrSink)(nil)
+
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+	s.zl = s.zl.WithOptions(zap.AddCallerSkip(info.CallDepth))
+}
+
+func (s *logrSink) Enabled(level int) bool {
+	if level > 1 && !vEnabled(clampV(level-1), 1+s.depth) {
+		return false
+	}
+	return s.zl.Core().Enabled(logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	if ce := s.zl.Check(logrLevel(level), msg); ce != nil {
+		ce.Write(handleFields(s.zl, keysAndValues)...)
+	}
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	ce := s.zl.Check(zapcore.ErrorLevel, msg)
+	if ce == nil {
+		return
+	}
+	if err != nil {
+		ce.Write(handleFields(s.zl, keysAndValues, ErrorField(err))...)
+		return
+	}
+	ce.Write(handleFields(s.zl, keysAndValues)...)
+}
+
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &logrSink{zl: s.zl.With(handleFields(s.zl, keysAndValues)...), depth: s.depth}
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	return &logrSink{zl: s.zl.Named(name), depth: s.depth}
+}
+
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	return &logrSink{zl: s.zl.WithOptions(zap.AddCallerSkip(depth)), depth: s.depth + depth}
+}
+
+// FromLogr 将logr.Logger转换为Logger,日志交给logr的实现输出,
+// Debug对应V(1),本包的V(n)对应V(n+1),Error及以上级别调用logr.Logger.Error
+func FromLogr(l logr.Logger) Logger {
+	//调用处由zap计算,zapLogger的方法多了一层调用
+	return NewLogger(zap.New(&logrCore{l: l}, zap.AddCaller(), zap.AddCallerSkip(1)))
+}
+
+//logrV 返回zap级别对应的logr的V级别,与logrLevel互逆
+func logrV(lvl zapcore.Level) int {
+	switch {
+	case lvl >= zapcore.InfoLevel:
+		return 0
+	case lvl == zapcore.DebugLevel:
+		return 1
+	}
+	n := int(zapcore.DebugLevel - 1 - lvl)
+	if n == 0 {
+		return 0 //V(0)默认开启
+	}
+	return n + 1
+}
+
+//logrCore 实现zapcore.Core,将日志写入logr.Logger
+type logrCore struct {
+	l      logr.Logger
+	fields []zapcore.Field
+}
+
+func (c *logrCore) Enabled(lvl zapcore.Level) bool {
+	if lvl >= zapcore.ErrorLevel {
+		return c.l.Enabled()
+	}
+	return c.l.V(logrV(lvl)).Enabled()
+}
+
+func (c *logrCore) With(fields []zapcore.Field) zapcore.Core {
+	return &logrCore{l: c.l, fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *logrCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *logrCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	l := c.l
+	if ent.Caller.Defined { //Info,Infow,V(n).Info等到Write的层数不同,按照zap计算的调用处确定
+		if depth, ok := callDepth(ent.Caller); ok {
+			l = l.WithCallDepth(depth)
+		}
+	}
+	if ent.LoggerName != "" {
+		l = l.WithName(ent.LoggerName)
+	}
+	if ent.Level < zapcore.ErrorLevel {
+		l.V(logrV(ent.Level)).Info(ent.Message, keysAndValuesOf(c.fields, fields)...)
+		return nil
+	}
+	//第一个log.Err记录的字段作为logr.Logger.Error的err参数,ErrorField等其他字段作为key-value
+	var err error
+	rest := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if e, ok := f.Interface.(error); ok && err == nil && f.Type == zapcore.ErrorType {
+			err = e
+			continue
+		}
+		rest = append(rest, f)
+	}
+	l.Error(err, ent.Message, keysAndValuesOf(c.fields, rest)...)
+	return nil
+}
+
+func (c *logrCore) Sync() error { return nil }
+
+//callDepth 返回调用callDepth的函数(logrCore.Write)到caller之间的层数
+func callDepth(caller zapcore.EntryCaller) (int, bool) {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:]) //跳过runtime.Callers,callDepth
+	frames := runtime.CallersFrames(pcs[:n])
+	for depth := 0; ; depth++ {
+		frame, more := frames.Next()
+		if frame.File == caller.File && frame.Line == caller.Line {
+			return depth, true
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
+
+//keysAndValuesOf 将zap的字段按顺序转换为key-value列表
+func keysAndValuesOf(groups ...[]zapcore.Field) []any {
+	var kvs []any
+	for _, fields := range groups {
+		for _, f := range fields {
+			encodeField(f, func(k string, v any) {
+				kvs = append(kvs, k, v)
+			})
+		}
+	}
+	return kvs
+}
+
+//encodeField 将单个zap字段编码为key-value,zap.Inline等展开为多个key的字段按key排序,保证输出的顺序稳定
+func encodeField(f zapcore.Field, fn func(k string, v any)) {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	if v, ok := enc.Fields[f.Key]; ok && len(enc.Fields) == 1 {
+		fn(f.Key, v)
+		return
+	}
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fn(k, enc.Fields[k])
+	}
+}
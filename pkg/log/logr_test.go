@@ -0,0 +1,122 @@
+package log
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+//newObservedLogger 与New创建的logger一样多一层调用,记录包括V级别在内的所有日志
+func newObservedLogger() (*zapLogger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.Level(-maxV - 2))
+	zl := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+	return &zapLogger{zapLogger: zl, infoLogger: infoLogger{log: zl, level: InfoLevel}}, logs
+}
+
+func TestToLogr(t *testing.T) {
+	l, logs := newObservedLogger()
+	SetVerbosity(1)
+	defer SetVerbosity(0)
+
+	lr := ToLogr(l).WithName("ctrl").WithValues("k", "v")
+	lr.Info("info message")
+	lr.V(1).Info("v1 message", "a", 1)
+	lr.V(2).Info("v2 message")
+	lr.V(3).Info("v3 message")
+	if lr.V(3).Enabled() {
+		t.Error("V(3) should be disabled with v=1")
+	}
+	lr.Error(errors.New("boom"), "error message")
+
+	entries := logs.All()
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+	wantLevels := []zapcore.Level{InfoLevel, DebugLevel, vLevel(1), ErrorLevel}
+	for i, e := range entries {
+		if e.Level != wantLevels[i] {
+			t.Errorf("%s: level %v, want %v", e.Message, e.Level, wantLevels[i])
+		}
+		if e.LoggerName != "ctrl" || e.ContextMap()["k"] != "v" {
+			t.Errorf("%s: name %q, fields %v", e.Message, e.LoggerName, e.ContextMap())
+		}
+		if filepath.Base(e.Caller.File) != "logr_test.go" {
+			t.Errorf("%s: caller %s, want logr_test.go", e.Message, e.Caller.File)
+		}
+	}
+	if _, ok := entries[3].ContextMap()["error"]; !ok {
+		t.Errorf("error entry should have an error field: %v", entries[3].ContextMap())
+	}
+}
+
+func TestLogrLevels(t *testing.T) {
+	tests := []struct {
+		v   int
+		lvl zapcore.Level
+	}{
+		{0, InfoLevel},
+		{1, DebugLevel},
+		{2, vLevel(1)},
+		{3, vLevel(2)},
+		{maxV + 1, vLevel(maxV)},
+	}
+	for _, tt := range tests {
+		if got := logrLevel(tt.v); got != tt.lvl {
+			t.Errorf("logrLevel(%d) = %v, want %v", tt.v, got, tt.lvl)
+		}
+		if got := logrV(tt.lvl); got != tt.v {
+			t.Errorf("logrV(%v) = %d, want %d", tt.lvl, got, tt.v)
+		}
+	}
+	//Info以上的级别和默认开启的V(0)都对应logr的V(0)
+	for _, lvl := range []zapcore.Level{WarnLevel, ErrorLevel, vLevel(0)} {
+		if got := logrV(lvl); got != 0 {
+			t.Errorf("logrV(%v) = %d, want 0", lvl, got)
+		}
+	}
+}
+
+func TestFromLogr(t *testing.T) {
+	var lines []string
+	lr := funcr.New(func(prefix, args string) {
+		lines = append(lines, prefix+" "+args)
+	}, funcr.Options{Verbosity: 2, LogCaller: funcr.All})
+	SetVerbosity(1)
+	defer SetVerbosity(0)
+
+	l := FromLogr(lr).WithName("db").WithValues("k", "v")
+	l.Info("info message", String("a", "b"), Int("c", 1), Bool("d", true))
+	l.Debug("debug message")
+	l.V(1).Info("v1 message")
+	l.V(2).Info("v2 message")
+	l.Error("error message", Err(errors.New("boom")))
+	l.Infow("infow message", "e", 2)
+
+	want := []string{
+		`db "level"=0 "msg"="info message" "k"="v" "a"="b" "c"=1 "d"=true`,
+		`db "level"=1 "msg"="debug message" "k"="v"`,
+		`db "level"=2 "msg"="v1 message" "k"="v"`,
+		`db "msg"="error message" "error"="boom" "k"="v"`,
+		`db "level"=0 "msg"="infow message" "k"="v" "e"=2`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(want), lines)
+	}
+	reCaller := regexp.MustCompile(`"caller"=\{"file"="([^"]+)" "line"=\d+\} `)
+	for i := range want {
+		m := reCaller.FindStringSubmatch(lines[i])
+		if m == nil || m[1] != "logr_test.go" {
+			t.Errorf("caller should point to the test: %q", lines[i])
+		}
+		if got := reCaller.ReplaceAllString(lines[i], ""); !strings.HasPrefix(got, want[i]) {
+			t.Errorf("got %q, want %q", got, want[i])
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package logtest 提供记录在内存中的log.Logger,用于在测试中断言输出的日志,example:
+//
+//	logs := logtest.Install(t, logtest.FailOnError())
+//	handler(ctx)
+//	if logs.FilterField(log.String(log.KeyRequestID, "req-1")).Len() == 0 {...}
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/leilei3167/basic/pkg/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry 一条记录的日志,包括级别,消息,logger名称,调用处以及字段,字段可以通过ContextMap获取
+type Entry = observer.LoggedEntry
+
+// Option 创建logger的选项
+type Option func(*options)
+
+type options struct {
+	level       log.Level
+	name        string
+	failOnError bool
+}
+
+// Level 记录的最低级别,默认为debug,开启的V级别按照info判断
+func Level(lvl log.Level) Option {
+	return func(o *options) { o.level = lvl }
+}
+
+// Name logger的名称
+func Name(name string) Option {
+	return func(o *options) { o.name = name }
+}
+
+// FailOnError 测试结束时还有error及以上级别的日志则测试失败,预期中的错误日志可以先通过Logs.Take取走
+func FailOnError() Option {
+	return func(o *options) { o.failOnError = true }
+}
+
+// New 创建将日志记录在内存中的logger
+func New(t testing.TB, opts ...Option) (log.Logger, *Logs) {
+	t.Helper()
+	o := options{level: log.DebugLevel}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	logs := &Logs{store: &store{}}
+	core := &recordCore{LevelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		if lvl < zapcore.DebugLevel { //V级别是否开启由log.SetVerbosity决定
+			lvl = zapcore.InfoLevel
+		}
+		return lvl >= o.level
+	}), store: logs.store}
+	//与log.New创建的logger一样,调用处需要跳过Logger的方法
+	zl := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1)).Named(o.name)
+	if o.failOnError {
+		t.Cleanup(func() {
+			for _, e := range logs.FilterMinLevel(log.ErrorLevel).All() {
+				t.Errorf("unexpected %s log: %s", e.Level.CapitalString(), format(e))
+			}
+		})
+	}
+	return log.NewLogger(zl), logs
+}
+
+// Install 创建logger并设置为包级别的默认logger,log.Info,log.L(ctx)等都会记录到返回的Logs中,
+// 测试结束时恢复原来的logger,修改了全局状态,不能用于并行的测试
+func Install(t testing.TB, opts ...Option) *Logs {
+	t.Helper()
+	l, logs := New(t, opts...)
+	t.Cleanup(log.ReplaceStd(l))
+	return logs
+}
+
+//store 保存记录的日志,Logs及其过滤后的视图共用
+type store struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+//recordCore 与observer的core一样,With添加的字段记录在Entry.Context中
+type recordCore struct {
+	zapcore.LevelEnabler
+	store   *store
+	context []zapcore.Field
+}
+
+func (c *recordCore) With(fields []zapcore.Field) zapcore.Core {
+	return &recordCore{
+		LevelEnabler: c.LevelEnabler,
+		store:        c.store,
+		context:      append(c.context[:len(c.context):len(c.context)], fields...),
+	}
+}
+
+func (c *recordCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *recordCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := Entry{Entry: ent, Context: append(c.context[:len(c.context):len(c.context)], fields...)}
+	c.store.mu.Lock()
+	c.store.entries = append(c.store.entries, e)
+	c.store.mu.Unlock()
+	return nil
+}
+
+func (c *recordCore) Sync() error { return nil }
+
+// Logs 记录的日志,Filter开头的方法返回共用同一份日志的视图,视图的TakeAll同样会从原来的Logs中取走日志
+type Logs struct {
+	store *store
+	match func(Entry) bool //为nil时匹配所有日志
+}
+
+func (l *Logs) matches(e Entry) bool {
+	return l.match == nil || l.match(e)
+}
+
+// Len 返回日志的条数
+func (l *Logs) Len() int { return len(l.All()) }
+
+// All 返回所有的日志
+func (l *Logs) All() []Entry {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	var ret []Entry
+	for _, e := range l.store.entries {
+		if l.matches(e) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// TakeAll 返回并清空所有的日志,过滤后的视图只取走视图中的日志
+func (l *Logs) TakeAll() []Entry {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	var taken, rest []Entry
+	for _, e := range l.store.entries {
+		if l.matches(e) {
+			taken = append(taken, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	l.store.entries = rest
+	return taken
+}
+
+// Take 返回并取走fn为true的日志,用于在FailOnError时取走预期中的错误日志,example:
+//
+//	logs.Take(func(e logtest.Entry) bool { return e.Message == "expected error" })
+func (l *Logs) Take(fn func(Entry) bool) []Entry {
+	return l.Filter(fn).TakeAll()
+}
+
+// Messages 返回所有日志的消息
+func (l *Logs) Messages() []string {
+	var msgs []string
+	for _, e := range l.All() {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// Filter 返回fn为true的日志
+func (l *Logs) Filter(fn func(Entry) bool) *Logs {
+	match := fn
+	if prev := l.match; prev != nil {
+		match = func(e Entry) bool { return prev(e) && fn(e) }
+	}
+	return &Logs{store: l.store, match: match}
+}
+
+// FilterLevel 返回指定级别的日志,V(n)的级别为 log.DebugLevel-1-n
+func (l *Logs) FilterLevel(lvl log.Level) *Logs {
+	return l.Filter(func(e Entry) bool { return e.Level == lvl })
+}
+
+// FilterMinLevel 返回指定级别及以上的日志
+func (l *Logs) FilterMinLevel(lvl log.Level) *Logs {
+	return l.Filter(func(e Entry) bool { return e.Level >= lvl })
+}
+
+// FilterName 返回logger名称为name的日志
+func (l *Logs) FilterName(name string) *Logs {
+	return l.Filter(func(e Entry) bool { return e.LoggerName == name })
+}
+
+// FilterMessage 返回消息为msg的日志
+func (l *Logs) FilterMessage(msg string) *Logs {
+	return l.Filter(func(e Entry) bool { return e.Message == msg })
+}
+
+// FilterMessageSnippet 返回消息包含snippet的日志
+func (l *Logs) FilterMessageSnippet(snippet string) *Logs {
+	return l.Filter(func(e Entry) bool { return strings.Contains(e.Message, snippet) })
+}
+
+// FilterField 返回包含field的日志,包括通过WithValues,L(ctx)添加的字段
+func (l *Logs) FilterField(field log.Field) *Logs {
+	return l.Filter(func(e Entry) bool {
+		for _, f := range e.Context {
+			if f.Equals(field) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterFieldKey 返回包含key字段的日志
+func (l *Logs) FilterFieldKey(key string) *Logs {
+	return l.Filter(func(e Entry) bool {
+		for _, f := range e.Context {
+			if f.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func format(e Entry) string {
+	var b strings.Builder
+	if e.LoggerName != "" {
+		b.WriteString(e.LoggerName + ": ")
+	}
+	b.WriteString(e.Message)
+	if fields := e.ContextMap(); len(fields) > 0 {
+		fmt.Fprintf(&b, " %v", fields)
+	}
+	if e.Caller.Defined {
+		b.WriteString(" (" + e.Caller.TrimmedPath() + ")")
+	}
+	return b.String()
+}
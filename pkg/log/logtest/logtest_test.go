@@ -0,0 +1,90 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leilei3167/basic/pkg/log"
+)
+
+func TestInstall(t *testing.T) {
+	logs := Install(t, FailOnError())
+
+	ctx := log.ContextWithRequestID(context.Background(), "req-1")
+	log.L(ctx).WithName("handler").Infow("request done", "status", 200)
+	log.Debug("debug message")
+	log.V(1).Info("disabled v1 message")
+
+	if got := logs.FilterField(log.String(log.KeyRequestID, "req-1")).Len(); got != 1 {
+		t.Errorf("got %d entries with requestID, want 1", got)
+	}
+	e := logs.FilterName("handler").All()
+	if len(e) != 1 || e[0].Message != "request done" || e[0].ContextMap()["status"] != int64(200) {
+		t.Errorf("unexpected entries: %v", e)
+	}
+	if !strings.HasPrefix(e[0].Caller.TrimmedPath(), "logtest/logtest_test.go") {
+		t.Errorf("caller should be the test file: %s", e[0].Caller.TrimmedPath())
+	}
+	if msgs := logs.FilterLevel(log.DebugLevel).Messages(); len(msgs) != 1 || msgs[0] != "debug message" {
+		t.Errorf("got debug messages %v", msgs)
+	}
+	if logs.FilterMessageSnippet("v1").Len() != 0 {
+		t.Error("V(1) should be disabled by default")
+	}
+
+	log.Error("expected error", log.Err(errors.New("boom")))
+	if logs.FilterMinLevel(log.ErrorLevel).FilterFieldKey("error").Len() != 1 {
+		t.Error("missing error entry")
+	}
+	n := logs.Len()
+	if taken := logs.Take(func(e Entry) bool { return e.Message == "expected error" }); len(taken) != 1 {
+		t.Errorf("Take: got %v", taken)
+	}
+	if logs.Len() != n-1 || logs.FilterMinLevel(log.ErrorLevel).Len() != 0 {
+		t.Errorf("Take should remove only the matching entry, left %v", logs.Messages())
+	}
+
+	//过滤后的视图与原来的Logs共用日志
+	debug := logs.FilterLevel(log.DebugLevel)
+	log.Debug("another debug message")
+	if debug.Len() != 2 {
+		t.Errorf("view should see new entries, got %v", debug.Messages())
+	}
+	debug.TakeAll()
+	if logs.FilterLevel(log.DebugLevel).Len() != 0 || logs.Len() != n-2 {
+		t.Errorf("TakeAll on a view should remove the entries from the original, left %v", logs.Messages())
+	}
+}
+
+//recorder 记录Errorf和Cleanup,用于测试FailOnError
+type recorder struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper()          {}
+func (r *recorder) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFailOnError(t *testing.T) {
+	r := &recorder{TB: t}
+	l, logs := New(r, Level(log.WarnLevel), Name("svc"), FailOnError())
+	l.Info("ignored")
+	l.Warn("warn message")
+	l.Error("unexpected", log.String("key", "value"))
+	for _, f := range r.cleanups {
+		f()
+	}
+	if logs.Len() != 2 || logs.FilterName("svc").Len() != 2 {
+		t.Errorf("got entries %v", logs.Messages())
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "svc: unexpected map[key:value]") {
+		t.Errorf("got errors %q", r.errors)
+	}
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//按logger名称覆盖日志级别,名称按"."分级匹配,如 http.access.v1 依次匹配 http.access.v1, http.access, http,
+//都没有匹配时使用全局的日志级别;名称相对于根logger(Options.Name),如 log.name=app 时 app.db 匹配 db
+
+const noOverride = math.MaxInt32
+
+type nameLevels struct {
+	base zap.AtomicLevel
+	root string //根logger的名称,匹配前从logger名称中去掉
+
+	mu     sync.Mutex
+	levels map[string]zapcore.Level
+
+	min   atomic.Int32             //所有覆盖中最低的级别,没有覆盖时为noOverride
+	cache atomic.Pointer[sync.Map] //name -> namedLevel,覆盖修改时整体替换
+}
+
+type namedLevel struct {
+	level zapcore.Level
+	ok    bool
+}
+
+func newNameLevels(base zap.AtomicLevel, root string, levels map[string]zapcore.Level) *nameLevels {
+	n := &nameLevels{base: base, root: root, levels: make(map[string]zapcore.Level, len(levels))}
+	for name, lvl := range levels {
+		n.levels[name] = lvl
+	}
+	n.reset()
+	return n
+}
+
+//parseLevels 将配置中的 {"db": "debug"} 转换为日志级别
+func parseLevels(levels map[string]string) (map[string]zapcore.Level, error) {
+	ret := make(map[string]zapcore.Level, len(levels))
+	for name, text := range levels {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(text)); err != nil {
+			return nil, fmt.Errorf("invalid level %q for logger %q: %v", text, name, err)
+		}
+		ret[name] = lvl
+	}
+	return ret, nil
+}
+
+//reset 在覆盖修改后重新计算最低级别并清空缓存,调用方需持有n.mu或者n尚未被使用
+func (n *nameLevels) reset() {
+	min := int32(noOverride)
+	for _, lvl := range n.levels {
+		if int32(lvl) < min {
+			min = int32(lvl)
+		}
+	}
+	n.min.Store(min)
+	n.cache.Store(&sync.Map{})
+}
+
+func (n *nameLevels) set(name string, lvl zapcore.Level) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.levels[name] = lvl
+	n.reset()
+}
+
+func (n *nameLevels) unset(name string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.levels, name)
+	n.reset()
+}
+
+//lookup 返回name匹配到的覆盖级别,结果会被缓存
+func (n *nameLevels) lookup(name string) (zapcore.Level, bool) {
+	cache := n.cache.Load()
+	if v, ok := cache.Load(name); ok {
+		nl := v.(namedLevel)
+		return nl.level, nl.ok
+	}
+
+	n.mu.Lock()
+	var nl namedLevel
+	for key := name; ; {
+		if lvl, ok := n.levels[key]; ok {
+			nl = namedLevel{level: lvl, ok: true}
+			break
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	n.mu.Unlock()
+
+	cache.Store(name, nl)
+	return nl.level, nl.ok
+}
+
+//relative 返回相对于根logger的名称,根logger自身为空
+func (n *nameLevels) relative(name string) string {
+	if n.root == "" {
+		return name
+	}
+	if name == n.root {
+		return ""
+	}
+	return strings.TrimPrefix(name, n.root+".")
+}
+
+//level 返回name实际生效的日志级别
+func (n *nameLevels) level(name string) zapcore.Level {
+	name = n.relative(name)
+	if n.min.Load() != noOverride && name != "" {
+		if lvl, ok := n.lookup(name); ok {
+			return lvl
+		}
+	}
+	return n.base.Level()
+}
+
+func (n *nameLevels) enabled(name string, lvl zapcore.Level) bool {
+	if n.min.Load() == noOverride || name == "" {
+		return n.base.Enabled(lvl)
+	}
+	return lvl >= n.level(name)
+}
+
+//anyEnabled 只要有任意一个logger可能开启lvl就返回true
+func (n *nameLevels) anyEnabled(lvl zapcore.Level) bool {
+	return n.base.Enabled(lvl) || int32(lvl) >= n.min.Load()
+}
+
+//overrides 返回所有覆盖,按名称排序
+func (n *nameLevels) overrides() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ret := make([]string, 0, len(n.levels))
+	for name, lvl := range n.levels {
+		ret = append(ret, name+"="+lvl.String())
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+//levelCore 按entry的logger名称过滤日志,被过滤的日志不会进入内部的core(编码,采样等)
+type levelCore struct {
+	zapcore.Core
+	levels *nameLevels
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.anyEnabled(enabledLevel(lvl))
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabled(ent.LoggerName, enabledLevel(ent.Level)) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// SetNamedLevel 修改名为name的logger及其子logger的日志级别,如 SetNamedLevel("db", DebugLevel)
+// 对 db, db.pool 等logger生效
+func SetNamedLevel(name string, level Level) {
+	if std.levels != nil {
+		std.levels.set(name, level)
+	}
+}
+
+// GetNamedLevel 返回名为name的logger实际生效的日志级别
+func GetNamedLevel(name string) Level {
+	if std.levels != nil {
+		return std.levels.level(name)
+	}
+	return GetLevel()
+}
+
+// UnsetNamedLevel 删除SetNamedLevel或者Options.Levels设置的覆盖,恢复使用全局的日志级别
+func UnsetNamedLevel(name string) {
+	if std.levels != nil {
+		std.levels.unset(name)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/spf13/pflag"
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"strings"
 )
@@ -23,6 +22,28 @@ const (
 	flagErrorOutputPaths  = "log.error-output-paths"
 	flagDevelopment       = "log.development"
 	flagName              = "log.name"
+	flagLevels            = "log.levels"
+	flagV                 = "log.v"
+	flagVModule           = "log.vmodule"
+	flagSinks             = "log.sinks"
+	flagTimeFormat        = "log.time-format"
+	flagKeyMessage        = "log.keys.message"
+	flagKeyLevel          = "log.keys.level"
+	flagKeyTime           = "log.keys.time"
+	flagKeyName           = "log.keys.name"
+	flagKeyCaller         = "log.keys.caller"
+	flagKeyStacktrace     = "log.keys.stacktrace"
+	flagSamplingDisable   = "log.sampling.disable"
+	flagSamplingInitial   = "log.sampling.initial"
+	flagSamplingAfter     = "log.sampling.thereafter"
+	flagSamplingTick      = "log.sampling.tick"
+	flagSamplingNever     = "log.sampling.never-sample-level"
+	flagSamplingReport    = "log.sampling.report-interval"
+	flagRotateMaxSize     = "log.rotate.max-size"
+	flagRotateMaxAge      = "log.rotate.max-age"
+	flagRotateMaxBackups  = "log.rotate.max-backups"
+	flagRotateCompress    = "log.rotate.compress"
+	flagRotateLocalTime   = "log.rotate.local-time"
 
 	consoleFormat = "console"
 	jsonFormat    = "json"
@@ -43,6 +64,22 @@ type Options struct {
 	EnableColor       bool   `json:"enable-color"       mapstructure:"enable-color"`
 	Development       bool   `json:"development"        mapstructure:"development"`
 	Name              string `json:"name"               mapstructure:"name"`
+	//按logger名称覆盖日志级别,名称按"."分级匹配,如 {"db": "debug", "http.access": "warn"}
+	Levels map[string]string `json:"levels"             mapstructure:"levels"`
+	//V(n)在n<=V时开启
+	V int `json:"v"                  mapstructure:"v"`
+	//按文件设置V级别,如 "server=2,pkg/db/*=4"
+	VModule string `json:"vmodule"            mapstructure:"vmodule"`
+	//多个输出目的地,每个可以指定级别,格式和颜色,配置后忽略OutputPaths,Format和EnableColor作为默认值
+	Sinks []SinkOptions `json:"sinks"              mapstructure:"sinks"`
+	//时间的格式,可以是time.Format的layout或者rfc3339,iso8601,millis等
+	TimeFormat string `json:"time-format"        mapstructure:"time-format"`
+	//日志中各个字段的key,如将timestamp改为ts
+	Keys EncoderKeys `json:"keys"               mapstructure:"keys"`
+	//日志采样配置
+	Sampling SamplingOptions `json:"sampling"           mapstructure:"sampling"`
+	//文件类输出路径的切割配置
+	Rotate RotateOptions `json:"rotate"             mapstructure:"rotate"`
 }
 
 func NewOptions() *Options {
@@ -56,6 +93,8 @@ func NewOptions() *Options {
 		EnableColor:       false,
 		Development:       false,
 		Name:              "",
+		TimeFormat:        defaultTimeFormat,
+		Sampling:          NewSamplingOptions(),
 	}
 }
 
@@ -68,10 +107,31 @@ func (o *Options) Validate() []error {
 		errs = append(errs, err)
 	}
 
+	if _, err := parseLevels(o.Levels); err != nil {
+		errs = append(errs, err)
+	}
+
 	format := strings.ToLower(o.Format)
 	if format != consoleFormat && format != jsonFormat {
 		errs = append(errs, fmt.Errorf("not a valid log format: %q", o.Format))
 	}
+	if _, err := parseVModule(o.VModule); err != nil {
+		errs = append(errs, err)
+	}
+	for _, sink := range o.Sinks {
+		if err := sink.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.Sampling.NeverSampleLevel != "" {
+		var never zapcore.Level
+		if err := never.UnmarshalText([]byte(o.Sampling.NeverSampleLevel)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.Rotate.MaxSize < 0 || o.Rotate.MaxAge < 0 || o.Rotate.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("log rotate options must not be negative: %+v", o.Rotate))
+	}
 	return errs
 }
 
@@ -86,60 +146,32 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&o.ErrorOutputPaths, flagErrorOutputPaths, o.ErrorOutputPaths, "设置zap自身的错误的输出路径")
 	fs.BoolVar(&o.Development, flagDevelopment, o.Development, "开发模式")
 	fs.StringVar(&o.Name, flagName, o.Name, "The name of the logger.")
+	fs.StringToStringVar(&o.Levels, flagLevels, o.Levels, "按logger名称覆盖日志级别,如 db=debug,http.access=warn")
+	fs.IntVar(&o.V, flagV, o.V, "V(n)日志的详细程度,V(n)在n<=v时输出")
+	fs.StringVar(&o.VModule, flagVModule, o.VModule, "按文件设置V日志的详细程度,如 server=2,pkg/db/*=4")
+	fs.Var(&sinksValue{sinks: &o.Sinks}, flagSinks, "输出目的地,可以指定多次,格式为 path,level=info,format=json,color=true,"+
+		"path可以是stdout,stderr,文件路径,tcp://host:port,udp://host:port,syslog://,path中的逗号需要转义为 \\,")
+	fs.StringVar(&o.TimeFormat, flagTimeFormat, o.TimeFormat, "时间的格式,time.Format的layout或者rfc3339,rfc3339nano,iso8601,millis,nanos,epoch")
+	fs.StringVar(&o.Keys.Message, flagKeyMessage, o.Keys.Message, "日志消息的key,默认message,为-时不输出")
+	fs.StringVar(&o.Keys.Level, flagKeyLevel, o.Keys.Level, "日志级别的key,默认level,为-时不输出")
+	fs.StringVar(&o.Keys.Time, flagKeyTime, o.Keys.Time, "时间的key,默认timestamp,为-时不输出")
+	fs.StringVar(&o.Keys.Name, flagKeyName, o.Keys.Name, "logger名称的key,默认logger,为-时不输出")
+	fs.StringVar(&o.Keys.Caller, flagKeyCaller, o.Keys.Caller, "调用者的key,默认caller,为-时不输出")
+	fs.StringVar(&o.Keys.Stacktrace, flagKeyStacktrace, o.Keys.Stacktrace, "堆栈的key,默认stacktrace,为-时不输出")
+	fs.BoolVar(&o.Sampling.Disable, flagSamplingDisable, o.Sampling.Disable, "关闭日志采样")
+	fs.IntVar(&o.Sampling.Initial, flagSamplingInitial, o.Sampling.Initial, "每个采样周期内相同的日志最先记录的条数")
+	fs.IntVar(&o.Sampling.Thereafter, flagSamplingAfter, o.Sampling.Thereafter, "超过initial后,每thereafter条记录一条")
+	fs.DurationVar(&o.Sampling.Tick, flagSamplingTick, o.Sampling.Tick, "采样的计数周期")
+	fs.StringVar(&o.Sampling.NeverSampleLevel, flagSamplingNever, o.Sampling.NeverSampleLevel, "达到该级别及以上的日志不会被采样")
+	fs.DurationVar(&o.Sampling.ReportInterval, flagSamplingReport, o.Sampling.ReportInterval, "输出被采样丢弃的日志汇总的间隔,为0时不输出")
+	fs.IntVar(&o.Rotate.MaxSize, flagRotateMaxSize, o.Rotate.MaxSize, "单个日志文件的最大大小(MB),为0时不切割")
+	fs.IntVar(&o.Rotate.MaxAge, flagRotateMaxAge, o.Rotate.MaxAge, "切割后的日志文件保留的天数,为0时不按时间清理")
+	fs.IntVar(&o.Rotate.MaxBackups, flagRotateMaxBackups, o.Rotate.MaxBackups, "切割后的日志文件保留的个数,为0时全部保留")
+	fs.BoolVar(&o.Rotate.Compress, flagRotateCompress, o.Rotate.Compress, "是否使用gzip压缩切割后的日志文件")
+	fs.BoolVar(&o.Rotate.LocalTime, flagRotateLocalTime, o.Rotate.LocalTime, "切割后的日志文件名使用本地时间,默认UTC")
 }
 
 func (o *Options) String() string {
 	data, _ := json.Marshal(o)
 	return string(data)
 }
-
-// Build 根据Options和配置文件创建一个全局的zap的 logger
-func (o *Options) Build() error {
-	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(o.Level)); err != nil {
-		zapLevel = zapcore.InfoLevel //默认info级别
-	}
-
-	encodeLevel := zapcore.CapitalLevelEncoder
-	if o.Format == consoleFormat && o.EnableColor { //如果开启颜色,并且输出为文本
-		encodeLevel = zapcore.CapitalColorLevelEncoder
-	}
-
-	//zap logger的配置
-	zc := &zap.Config{
-		Level:             zap.NewAtomicLevelAt(zapLevel),
-		Development:       o.Development,
-		DisableCaller:     o.DisableCaller,
-		DisableStacktrace: o.DisableStacktrace,
-		Sampling: &zap.SamplingConfig{ //采样策略
-			Initial:    100,
-			Thereafter: 100,
-		},
-		//自定义编码输出的选项
-		Encoding: o.Format,
-		EncoderConfig: zapcore.EncoderConfig{
-			MessageKey:     "message",
-			LevelKey:       "level",
-			TimeKey:        "timestamp",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    encodeLevel,
-			EncodeTime:     timeEncoder,
-			EncodeDuration: milliSecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-			EncodeName:     zapcore.FullNameEncoder,
-		},
-		OutputPaths:      o.OutputPaths,
-		ErrorOutputPaths: o.ErrorOutputPaths,
-	}
-
-	logger, err := zc.Build(zap.AddStacktrace(zapcore.PanicLevel)) //只在panicLevel打印堆栈
-	if err != nil {
-		return err
-	}
-	zap.RedirectStdLog(logger.Named(o.Name)) //增加logger的名字
-	zap.ReplaceGlobals(logger)               //将zap全局的logger替换为我们自己创建的
-	return nil
-}
@@ -0,0 +1,364 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//日志文件的滚动切割,文件类的输出路径都会通过rotate sink打开,
+//超过MaxSize时将当前文件重命名为 name-2006-01-02T15-04-05.000.ext 并创建新文件,
+//同一毫秒内多次切割时依次顺延1毫秒,保证备份文件名不重复且按时间有序
+
+const (
+	rotateScheme     = "rotate"
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1024 * 1024
+)
+
+// RotateOptions 日志文件的切割配置,只对文件类的输出路径生效
+type RotateOptions struct {
+	//单个日志文件的最大大小,单位MB,为0时不按大小切割
+	MaxSize int `json:"max-size"    mapstructure:"max-size"`
+	//切割后的文件最多保留的天数,为0时不按时间清理
+	MaxAge int `json:"max-age"     mapstructure:"max-age"`
+	//切割后的文件最多保留的个数,为0时全部保留
+	MaxBackups int `json:"max-backups" mapstructure:"max-backups"`
+	//是否使用gzip压缩切割后的文件
+	Compress bool `json:"compress"    mapstructure:"compress"`
+	//切割后的文件名使用本地时间,默认使用UTC时间
+	LocalTime bool `json:"local-time"  mapstructure:"local-time"`
+}
+
+func init() {
+	if err := zap.RegisterSink(rotateScheme, newRotateSink); err != nil {
+		panic(err)
+	}
+}
+
+//sinkPaths 将文件类的输出路径转换为rotate sink的URL,stdout,stderr以及其他scheme的路径保持不变
+func (r RotateOptions) sinkPaths(paths []string) []string {
+	ret := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "stdout" || p == "stderr" || strings.Contains(p, "://") {
+			ret = append(ret, p)
+			continue
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			ret = append(ret, p)
+			continue
+		}
+		q := url.Values{}
+		q.Set("max-size", strconv.Itoa(r.MaxSize))
+		q.Set("max-age", strconv.Itoa(r.MaxAge))
+		q.Set("max-backups", strconv.Itoa(r.MaxBackups))
+		q.Set("compress", strconv.FormatBool(r.Compress))
+		q.Set("local-time", strconv.FormatBool(r.LocalTime))
+		path := filepath.ToSlash(abs)
+		if !strings.HasPrefix(path, "/") { //windows下的 C:/logs/app.log
+			path = "/" + path
+		}
+		u := url.URL{Scheme: rotateScheme, Path: path, RawQuery: q.Encode()}
+		ret = append(ret, u.String())
+	}
+	return ret
+}
+
+var (
+	rollingMu    sync.Mutex
+	rollingFiles = map[string]*rollingFile{} //同一个文件只有一个rollingFile,避免多个logger同时切割
+)
+
+func newRotateSink(u *url.URL) (zap.Sink, error) {
+	q := u.Query()
+	var opts RotateOptions
+	opts.MaxSize, _ = strconv.Atoi(q.Get("max-size"))
+	opts.MaxAge, _ = strconv.Atoi(q.Get("max-age"))
+	opts.MaxBackups, _ = strconv.Atoi(q.Get("max-backups"))
+	opts.Compress, _ = strconv.ParseBool(q.Get("compress"))
+	opts.LocalTime, _ = strconv.ParseBool(q.Get("local-time"))
+
+	path := u.Path
+	if len(path) > 2 && path[2] == ':' {
+		path = path[1:]
+	}
+	filename := filepath.FromSlash(path)
+	rollingMu.Lock()
+	defer rollingMu.Unlock()
+
+	if r, ok := rollingFiles[filename]; ok {
+		r.mu.Lock()
+		r.opts = opts //以最后一次的配置为准
+		r.refs++
+		r.mu.Unlock()
+		return r, nil
+	}
+	r := &rollingFile{filename: filename, opts: opts, refs: 1}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	rollingFiles[filename] = r
+	return r, nil
+}
+
+// Reopen 关闭并重新打开所有的日志文件,配合外部的logrotate使用
+func Reopen() error {
+	rollingMu.Lock()
+	defer rollingMu.Unlock()
+
+	var errs []string
+	for _, r := range rollingFiles {
+		if err := r.Reopen(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("reopen log files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//rollingFile 实现zap.Sink,写入超过MaxSize时切割文件
+type rollingFile struct {
+	mu       sync.Mutex
+	filename string
+	opts     RotateOptions
+	file     *os.File
+	size     int64
+	refs     int
+
+	millMu sync.Mutex //串行的清理和压缩切割后的文件
+}
+
+func (r *rollingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rollingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if max := int64(r.opts.MaxSize) * megabyte; max > 0 && r.size > 0 && r.size+int64(len(p)) > max {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+//rotate 将当前文件重命名为备份文件并打开新文件,调用方需持有r.mu
+func (r *rollingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	if err := os.Rename(r.filename, r.backupName(r.now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	go r.mill(r.opts)
+	return nil
+}
+
+func (r *rollingFile) now() time.Time {
+	if r.opts.LocalTime {
+		return time.Now()
+	}
+	return time.Now().UTC()
+}
+
+func (r *rollingFile) backupName(t time.Time) string {
+	dir, prefix, ext := r.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		if !exists(name) && !exists(name+compressSuffix) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+//nameParts 将 /var/log/app.log 拆分为 /var/log, app-, .log
+func (r *rollingFile) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(r.filename)
+	base := filepath.Base(r.filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+type backup struct {
+	path string
+	t    time.Time
+}
+
+//backups 返回所有切割后的文件,按时间从新到旧排序
+func (r *rollingFile) backups(local bool) ([]backup, error) {
+	dir, prefix, ext := r.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if local {
+		loc = time.Local
+	}
+
+	var ret []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, compressSuffix)
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(ts, ext), loc)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, backup{path: filepath.Join(dir, name), t: t})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].t.After(ret[j].t) })
+	return ret, nil
+}
+
+//mill 清理超过MaxBackups和MaxAge的文件,并压缩未压缩的文件
+func (r *rollingFile) mill(opts RotateOptions) {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+
+	backups, err := r.backups(opts.LocalTime)
+	if err != nil {
+		return
+	}
+	var cutoff time.Time
+	if opts.MaxAge > 0 {
+		cutoff = time.Now().Add(-time.Duration(opts.MaxAge) * 24 * time.Hour)
+	}
+	for i, b := range backups {
+		if (opts.MaxBackups > 0 && i >= opts.MaxBackups) || (!cutoff.IsZero() && b.t.Before(cutoff)) {
+			_ = os.Remove(b.path)
+			continue
+		}
+		if opts.Compress && !strings.HasSuffix(b.path, compressSuffix) {
+			_ = compressFile(b.path)
+		}
+	}
+}
+
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// Reopen 关闭并重新打开日志文件,文件被外部移走后会创建新文件
+func (r *rollingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+	return r.open()
+}
+
+func (r *rollingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Sync()
+}
+
+//Close 在最后一个引用关闭时才真正关闭文件
+func (r *rollingFile) Close() error {
+	rollingMu.Lock()
+	defer rollingMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refs--
+	if r.refs > 0 {
+		return nil
+	}
+	delete(rollingFiles, r.filename)
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	opts := NewOptions()
+	opts.OutputPaths = []string{filename}
+	opts.DisableCaller = true
+	opts.Rotate = RotateOptions{MaxSize: 1, MaxBackups: 2, Compress: true}
+	l := New(opts)
+
+	line := strings.Repeat("x", 100*1024)
+	for i := 0; i < 40; i++ {
+		l.Info(line) //同一毫秒内切割多次时备份文件名不能重复
+	}
+	l.Flush()
+
+	var backups []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) { //清理和压缩是异步进行的
+		backups, _ = filepath.Glob(filepath.Join(dir, "app-*.log*"))
+		if len(backups) == 2 && strings.HasSuffix(backups[0], ".gz") && strings.HasSuffix(backups[1], ".gz") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("want 2 compressed backups, got %v", backups)
+	}
+	for _, b := range backups {
+		if !strings.HasSuffix(b, ".gz") {
+			t.Errorf("backup %s is not compressed", b)
+		}
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 1024*1024 {
+		t.Errorf("current file should not exceed max size: %v", info.Size())
+	}
+
+	//模拟外部logrotate移走文件,Reopen后应创建新文件
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after reopen")
+	l.Flush()
+	data, err := os.ReadFile(filename)
+	if err != nil || !strings.Contains(string(data), "after reopen") {
+		t.Errorf("want new file after reopen, got %q %v", data, err)
+	}
+}
+
+func TestBackupName(t *testing.T) {
+	r := &rollingFile{filename: filepath.Join(t.TempDir(), "app.log")}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	first := r.backupName(now)
+	if err := os.WriteFile(first+compressSuffix, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if second := r.backupName(now); second == first || !strings.HasSuffix(second, "app-2024-01-02T03-04-05.007.log") {
+		t.Errorf("want the next millisecond after %s, got %s", first, second)
+	}
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//日志采样,相同级别和消息的日志在Tick内超过Initial条后,每Thereafter条只记录一条,
+//达到NeverSampleLevel的日志不会被采样
+
+// SamplingOptions 日志采样的配置
+type SamplingOptions struct {
+	//关闭采样,记录所有日志
+	Disable bool `json:"disable"            mapstructure:"disable"`
+	//每个Tick内相同的日志最先记录的条数
+	Initial int `json:"initial"            mapstructure:"initial"`
+	//超过Initial后,每Thereafter条记录一条,为0时不采样
+	Thereafter int `json:"thereafter"         mapstructure:"thereafter"`
+	//采样的计数周期
+	Tick time.Duration `json:"tick"               mapstructure:"tick"`
+	//达到该级别及以上的日志不会被采样
+	NeverSampleLevel string `json:"never-sample-level" mapstructure:"never-sample-level"`
+	//输出被丢弃日志汇总的间隔,为0时不输出
+	ReportInterval time.Duration `json:"report-interval"    mapstructure:"report-interval"`
+}
+
+func NewSamplingOptions() SamplingOptions {
+	return SamplingOptions{
+		Initial:          100,
+		Thereafter:       100,
+		Tick:             time.Second,
+		NeverSampleLevel: zapcore.ErrorLevel.String(),
+		ReportInterval:   time.Minute,
+	}
+}
+
+//wrap 返回采样后的core,关闭采样时原样返回
+func (s SamplingOptions) wrap(core zapcore.Core) zapcore.Core {
+	if s.Disable || s.Thereafter <= 0 {
+		return core
+	}
+	never := zapcore.ErrorLevel
+	if s.NeverSampleLevel != "" {
+		_ = never.UnmarshalText([]byte(s.NeverSampleLevel)) //错误由Validate检查
+	}
+	tick := s.Tick
+	if tick <= 0 {
+		tick = time.Second
+	}
+	report := &droppedReport{core: core, interval: int64(s.ReportInterval)}
+	report.last.Store(time.Now().UnixNano())
+
+	return &samplingCore{
+		Core:    core,
+		sampled: zapcore.NewSamplerWithOptions(core, tick, s.Initial, s.Thereafter, zapcore.SamplerHook(report.count)),
+		never:   never,
+		report:  report,
+	}
+}
+
+//samplingCore 只对Debug到never之间的日志采样
+type samplingCore struct {
+	zapcore.Core
+	sampled zapcore.Core
+	never   zapcore.Level
+	report  *droppedReport //With得到的core共用
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampled: c.sampled.With(fields), never: c.never, report: c.report}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= c.never || ent.Level < zapcore.DebugLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if c.report.due(ent.Time) {
+		c.report.write(ent.Time)
+	}
+	return c.sampled.Check(ent, ce)
+}
+
+//Sync 先输出尚未汇总的丢弃条数,保证Flush之后不会遗漏
+func (c *samplingCore) Sync() error {
+	if c.report.interval > 0 {
+		c.report.write(time.Now())
+	}
+	return c.Core.Sync()
+}
+
+var droppedTotal atomic.Uint64
+
+// DroppedEntries 返回进程启动以来被采样丢弃的日志条数
+func DroppedEntries() uint64 {
+	return droppedTotal.Load()
+}
+
+//droppedReport 记录一个logger被采样丢弃的日志,超过interval后由下一条采样的日志触发汇总,
+//不需要额外的goroutine,随logger一起被回收
+type droppedReport struct {
+	core     zapcore.Core                                               //未经With的core,汇总不应带有某个子logger的字段
+	interval int64                                                      //输出汇总的间隔,为0时不输出
+	last     atomic.Int64                                               //上次汇总的时间
+	dropped  [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Uint64 //按级别计数
+}
+
+func (r *droppedReport) count(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped == 0 || ent.Level < zapcore.DebugLevel || ent.Level > zapcore.FatalLevel {
+		return
+	}
+	r.dropped[ent.Level-zapcore.DebugLevel].Add(1)
+	droppedTotal.Add(1)
+}
+
+//due 判断是否到了汇总的时间,同一周期内只有一个调用方返回true
+func (r *droppedReport) due(now time.Time) bool {
+	if r.interval <= 0 {
+		return false
+	}
+	last := r.last.Load()
+	return now.UnixNano()-last >= r.interval && r.last.CompareAndSwap(last, now.UnixNano())
+}
+
+//write 将上次汇总以来被丢弃的日志条数写入r.core,不经过采样
+func (r *droppedReport) write(now time.Time) {
+	fields := r.take()
+	if len(fields) == 0 {
+		return
+	}
+	ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: now, Message: "log entries dropped by sampling"}
+	if ce := r.core.Check(ent, nil); ce != nil {
+		ce.Write(append(fields, zap.Duration("interval", time.Duration(r.interval)))...)
+	}
+}
+
+//take 返回上次汇总以来被丢弃的日志条数并清零
+func (r *droppedReport) take() []Field {
+	var fields []Field
+	for i := range r.dropped {
+		if n := r.dropped[i].Swap(0); n > 0 {
+			fields = append(fields, zap.Uint64((zapcore.Level(i)+zapcore.DebugLevel).String(), n))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampling(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	opts := SamplingOptions{Initial: 2, Thereafter: 5, Tick: time.Minute, NeverSampleLevel: "error"}
+	l := zap.New(opts.wrap(core))
+
+	before := DroppedEntries()
+	for i := 0; i < 12; i++ {
+		l.Info("hot loop")
+		l.Error("never sampled")
+	}
+
+	//前2条以及之后每5条中的1条: 第1,2,7,12条
+	if n := logs.FilterMessage("hot loop").Len(); n != 4 {
+		t.Errorf("sampled info: got %d, want 4", n)
+	}
+	if n := logs.FilterMessage("never sampled").Len(); n != 12 {
+		t.Errorf("error entries should never be sampled, got %d", n)
+	}
+	if n := DroppedEntries() - before; n != 8 {
+		t.Errorf("dropped: got %d, want 8", n)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := logs.FilterMessage("log entries dropped by sampling").Len(); n != 0 {
+		t.Errorf("report interval 0 should not write a summary, got %d", n)
+	}
+
+	//汇总由Sync或者间隔之后的下一条日志触发,每个logger单独统计
+	opts.ReportInterval = time.Hour
+	l = zap.New(opts.wrap(core))
+	child := l.With(zap.String("requestID", "unrelated"))
+	for i := 0; i < 12; i++ {
+		child.Info("hot loop")
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	reports := logs.FilterMessage("log entries dropped by sampling").AllUntimed()
+	if len(reports) != 1 || reports[0].ContextMap()["info"] != uint64(8) {
+		t.Fatalf("unexpected dropped summary %v", reports)
+	}
+	if _, ok := reports[0].ContextMap()["requestID"]; ok {
+		t.Errorf("dropped summary should not carry fields of a child logger: %v", reports[0].Context)
+	}
+
+	opts.Disable = true
+	if _, ok := opts.wrap(core).(*samplingCore); ok {
+		t.Error("disabled sampling should return the core as is")
+	}
+}
@@ -0,0 +1,74 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleLevelSignals 收到SIGUSR1时将日志级别调低一级(输出更多日志),收到SIGUSR2时调高一级,
+// 返回的函数用于停止监听,example:
+//
+//	kill -USR1 <pid>
+func HandleLevelSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				delta := 1
+				if sig == syscall.SIGUSR1 {
+					delta = -1
+				}
+				lvl := stepLevel(delta)
+				//调高到Error以上时这条日志不会输出
+				Warn("log level changed by signal", String("signal", sig.String()), String("level", lvl.String()))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// ReopenOnSIGHUP 收到SIGHUP信号时调用Reopen,返回的函数用于停止监听,example:
+//
+//	stop := log.ReopenOnSIGHUP()
+//	defer stop()
+func ReopenOnSIGHUP() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := Reopen(); err != nil {
+					Error("reopen log files failed", Err(err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package log
+
+// HandleLevelSignals windows不支持SIGUSR1和SIGUSR2,不做任何处理,请使用LevelHandler
+func HandleLevelSignals() (stop func()) {
+	return func() {}
+}
+
+// ReopenOnSIGHUP windows不支持SIGHUP,不做任何处理,请直接调用Reopen
+func ReopenOnSIGHUP() (stop func()) {
+	return func() {}
+}
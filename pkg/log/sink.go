@@ -0,0 +1,287 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//多个输出目的地,每个目的地可以有各自的级别,格式和颜色,最终通过zapcore.NewTee合并
+
+const (
+	syslogScheme = "syslog"
+	netTimeout   = 3 * time.Second
+)
+
+// SinkOptions 单个输出目的地的配置,Path支持:
+//   - stdout, stderr
+//   - 文件路径,按照Options.Rotate切割
+//   - tcp://host:port, udp://host:port 每条日志写入一次
+//   - syslog:// 本机的syslog, syslog://host:514?network=udp&tag=app&facility=local0 远程的syslog
+type SinkOptions struct {
+	Path string `json:"path"         mapstructure:"path"`
+	//该目的地的最低级别,只能比全局的级别更高,例如全局debug时,stdout为info,文件为debug
+	Level string `json:"level"        mapstructure:"level"`
+	//console或json,为空时使用Options.Format
+	Format      string `json:"format"       mapstructure:"format"`
+	EnableColor bool   `json:"enable-color" mapstructure:"enable-color"`
+}
+
+func (s SinkOptions) validate() error {
+	if s.Path == "" {
+		return fmt.Errorf("log sink path is required")
+	}
+	if s.Level != "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(s.Level)); err != nil {
+			return fmt.Errorf("log sink %q: %v", s.Path, err)
+		}
+	}
+	if f := strings.ToLower(s.Format); f != "" && f != consoleFormat && f != jsonFormat {
+		return fmt.Errorf("log sink %q: not a valid log format: %q", s.Path, s.Format)
+	}
+	return nil
+}
+
+//sinks 返回所有的输出目的地,没有配置Sinks时由OutputPaths生成
+func (o *Options) sinks() []SinkOptions {
+	if len(o.Sinks) > 0 {
+		return o.Sinks
+	}
+	sinks := make([]SinkOptions, 0, len(o.OutputPaths))
+	for _, p := range o.OutputPaths {
+		sinks = append(sinks, SinkOptions{Path: p, Format: o.Format, EnableColor: o.EnableColor})
+	}
+	return sinks
+}
+
+//buildCore 为每个目的地创建core并合并,出错时关闭已经打开的目的地,levels用于决定ErrorField是否记录堆栈
+func (o *Options) buildCore(levels *nameLevels) (zapcore.Core, error) {
+	var (
+		cores   []zapcore.Core
+		closers []func()
+	)
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	for _, s := range o.sinks() {
+		if err := s.validate(); err != nil {
+			closeAll()
+			return nil, err
+		}
+		format := s.Format
+		if format == "" {
+			format = o.Format
+		}
+		enc, err := o.encoder(format, s.EnableColor)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		//内部的core需要放行所有级别,全局的级别由levelCore过滤
+		lvl := sinkLevel(math.MinInt8)
+		if s.Level != "" {
+			var sl zapcore.Level
+			_ = sl.UnmarshalText([]byte(s.Level))
+			lvl = sinkLevel(sl)
+		}
+
+		if strings.HasPrefix(s.Path, syslogScheme+"://") || s.Path == syslogScheme {
+			core, closer, err := newSyslogCore(s.Path, enc, lvl)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			cores = append(cores, &errorCore{Core: core, levels: levels})
+			closers = append(closers, closer)
+			continue
+		}
+		ws, closer, err := o.openSinks([]string{s.Path})
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		cores = append(cores, &errorCore{Core: zapcore.NewCore(enc, ws, lvl), levels: levels})
+		closers = append(closers, closer)
+	}
+	if len(cores) == 1 {
+		return cores[0], nil
+	}
+	return zapcore.NewTee(cores...), nil
+}
+
+//openSinks 打开输出路径并合并,tcp和udp由本包直接打开,不通过zap.RegisterSink注册,
+//避免与其他库注册的同名scheme冲突
+func (o *Options) openSinks(paths []string) (zapcore.WriteSyncer, func(), error) {
+	var (
+		syncers []zapcore.WriteSyncer
+		closers []func()
+		rest    []string
+	)
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+	for _, p := range paths {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "tcp" && u.Scheme != "udp") {
+			rest = append(rest, p)
+			continue
+		}
+		sink, err := newNetSink(u)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		syncers = append(syncers, sink)
+		closers = append(closers, func() { _ = sink.Close() })
+	}
+	if len(rest) > 0 || len(syncers) == 0 {
+		ws, closer, err := zap.Open(o.Rotate.sinkPaths(rest)...)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		syncers = append(syncers, ws)
+		closers = append(closers, closer)
+	}
+	return zap.CombineWriteSyncers(syncers...), closeAll, nil
+}
+
+//sinksValue 实现pflag.Value,每个值的格式为 path,level=info,format=json,color=true,
+//path中的逗号需要转义为 \,
+type sinksValue struct {
+	sinks   *[]SinkOptions
+	changed bool
+}
+
+func (v *sinksValue) Set(val string) error {
+	parts := splitEscaped(val)
+	s := SinkOptions{Path: parts[0]}
+	for _, kv := range parts[1:] {
+		k, value, _ := strings.Cut(kv, "=")
+		switch k {
+		case "level":
+			s.Level = value
+		case "format":
+			s.Format = value
+		case "color":
+			color, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid color %q: %v", value, err)
+			}
+			s.EnableColor = color
+		default:
+			return fmt.Errorf("unknown sink option %q", k)
+		}
+	}
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if !v.changed { //命令行指定时覆盖配置中的默认值
+		*v.sinks = nil
+		v.changed = true
+	}
+	*v.sinks = append(*v.sinks, s)
+	return nil
+}
+
+func (v *sinksValue) String() string {
+	var ret []string
+	for _, s := range *v.sinks {
+		str := strings.ReplaceAll(s.Path, ",", `\,`)
+		if s.Level != "" {
+			str += ",level=" + s.Level
+		}
+		if s.Format != "" {
+			str += ",format=" + s.Format
+		}
+		if s.EnableColor {
+			str += ",color=true"
+		}
+		ret = append(ret, str)
+	}
+	return "[" + strings.Join(ret, " ") + "]"
+}
+
+func (v *sinksValue) Type() string { return "sinks" }
+
+//splitEscaped 按逗号分割,转义的 \, 作为逗号本身而不是分隔符
+func splitEscaped(val string) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+	)
+	for i := 0; i < len(val); i++ {
+		switch {
+		case val[i] == '\\' && i+1 < len(val) && val[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case val[i] == ',':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(val[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+//netSink 将日志写入tcp或udp连接,连接断开后在下一次写入时重连
+type netSink struct {
+	network, addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newNetSink(u *url.URL) (zap.Sink, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("log sink %q: host is required", u.String())
+	}
+	return &netSink{network: u.Scheme, addr: u.Host}, nil
+}
+
+func (s *netSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, netTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(netTimeout))
+	n, err := s.conn.Write(p)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return n, err
+}
+
+func (s *netSink) Sync() error { return nil }
+
+func (s *netSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
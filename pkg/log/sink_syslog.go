@@ -0,0 +1,94 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var facilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+	"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3, "local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+//newSyslogCore 创建写入syslog的core,日志级别映射为syslog的severity
+func newSyslogCore(path string, enc zapcore.Encoder, lvl zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := u.Query()
+	facility := syslog.LOG_USER
+	if name := q.Get("facility"); name != "" {
+		f, ok := facilities[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("log sink %q: unknown syslog facility %q", path, name)
+		}
+		facility = f
+	}
+	network := ""
+	if u.Host != "" {
+		network = q.Get("network")
+		if network == "" {
+			network = "udp"
+		}
+	}
+
+	w, err := syslog.Dial(network, u.Host, facility|syslog.LOG_INFO, q.Get("tag"))
+	if err != nil {
+		return nil, nil, err
+	}
+	core := &syslogCore{LevelEnabler: lvl, enc: enc, w: w}
+	return core, func() { w.Close() }, nil
+}
+
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslog.Writer
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &syslogCore{LevelEnabler: c.LevelEnabler, enc: c.enc.Clone(), w: c.w}
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+	}
+	return clone
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	msg := buf.String()
+	buf.Free()
+
+	switch {
+	case ent.Level <= zapcore.DebugLevel:
+		return c.w.Debug(msg)
+	case ent.Level == zapcore.InfoLevel:
+		return c.w.Info(msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.w.Warning(msg)
+	case ent.Level == zapcore.ErrorLevel:
+		return c.w.Err(msg)
+	default:
+		return c.w.Crit(msg)
+	}
+}
+
+func (c *syslogCore) Sync() error { return nil }
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package log
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogCore(path string, _ zapcore.Encoder, _ zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	return nil, nil, fmt.Errorf("log sink %q: syslog is not supported on this platform", path)
+}
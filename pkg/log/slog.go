@@ -0,0 +1,234 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+//slog与Logger的相互转换,slog的Debug,Info,Warn,Error对应相同的级别,
+//低于Debug的级别对应V级别: LevelDebug-1为V(0),LevelDebug-2为V(1),以此类推
+
+//zapLevelOf 返回slog级别对应的zap级别
+func zapLevelOf(level slog.Level) zapcore.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	case level >= slog.LevelDebug:
+		return zapcore.DebugLevel
+	}
+	return vLevel(clampV(int(slog.LevelDebug - 1 - level)))
+}
+
+//slogLevelOf 返回zap级别对应的slog级别
+func slogLevelOf(lvl zapcore.Level) slog.Level {
+	switch {
+	case lvl >= zapcore.ErrorLevel:
+		return slog.LevelError
+	case lvl == zapcore.WarnLevel:
+		return slog.LevelWarn
+	case lvl == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case lvl == zapcore.DebugLevel:
+		return slog.LevelDebug
+	}
+	return slog.LevelDebug - 1 - slog.Level(zapcore.DebugLevel-1-lvl)
+}
+
+// NewSlogHandler 返回使用Logger输出的slog.Handler,handler的Handle会从context中提取requestID等字段,
+// l不是本包创建的Logger时使用默认的logger
+func NewSlogHandler(l Logger) slog.Handler {
+	h := &slogHandler{zl: zapOf(l)}
+	if zl, ok := l.(*zapLogger); ok && zl != nil {
+		h.ctxFields = zl.ctxFields
+	}
+	return h
+}
+
+// ToSlog 将Logger转换为*slog.Logger,example:
+//
+//	slog.SetDefault(log.ToSlog(log.WithName("slog")))
+func ToSlog(l Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+//slogHandler 实现slog.Handler,fields为WithAttrs和WithGroup添加的字段,group对应zap.Namespace,
+//context中的字段需要输出在最外层,因此不能提前通过zap.Logger.With添加,
+//ctxFields为l通过L(ctx)已经添加过的字段,Handle时不再重复添加
+type slogHandler struct {
+	zl        *zap.Logger
+	fields    []zapcore.Field
+	ctxFields []Field
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	lvl := zapLevelOf(level)
+	//V级别只按照全局的--log.v判断,slog在Enabled中拿不到调用处
+	if lvl < zapcore.DebugLevel && zapcore.DebugLevel-1-lvl > zapcore.Level(verbosity.Load()) {
+		return false
+	}
+	return h.zl.Core().Enabled(lvl)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ce := h.zl.Check(zapLevelOf(r.Level), r.Message)
+	if ce == nil {
+		return nil
+	}
+	if !r.Time.IsZero() {
+		ce.Entry.Time = r.Time
+	}
+	//调用处以slog记录的为准,关闭了caller时不输出
+	if ce.Entry.Caller.Defined && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	var fields []zapcore.Field
+	if ctx != nil {
+		fields = append(fields, missingFields(h.ctxFields, extractFields(ctx))...)
+	}
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	return &slogHandler{zl: h.zl, fields: fields, ctxFields: h.ctxFields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{zl: h.zl, fields: append(h.fields[:len(h.fields):len(h.fields)], zap.Namespace(name)),
+		ctxFields: h.ctxFields}
+}
+
+//appendAttr 将slog.Attr转换为zap的字段,key为空的group展开到当前层级
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, attrGroup(attrs)))
+	}
+	if err, ok := v.Any().(error); ok {
+		return append(fields, zap.NamedError(a.Key, err))
+	}
+	return append(fields, zap.Any(a.Key, v.Any()))
+}
+
+type attrGroup []slog.Attr
+
+func (g attrGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range appendAttr(nil, slog.Attr{Value: slog.GroupValue(g...)}) {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// FromSlog 将slog.Handler转换为Logger,日志交给handler输出,logger的名称记录在logger字段中
+func FromSlog(h slog.Handler) Logger {
+	//zapLogger的方法多了一层调用
+	return NewLogger(zap.New(&slogCore{h: h}, zap.AddCaller(), zap.AddCallerSkip(1)))
+}
+
+//slogCore 实现zapcore.Core,将日志写入slog.Handler
+type slogCore struct {
+	h slog.Handler
+}
+
+func (c *slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), slogLevelOf(lvl))
+}
+
+//With zap.Namespace之后的字段都在该group中,对应slog.Handler.WithGroup
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	h := c.h
+	start := 0
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			h = h.WithAttrs(attrsOf(fields[start:i])).WithGroup(f.Key)
+			start = i + 1
+		}
+	}
+	return &slogCore{h: h.WithAttrs(attrsOf(fields[start:]))}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevelOf(ent.Level), ent.Message, ent.Caller.PC)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+	r.AddAttrs(attrsOf(fields)...)
+	if ent.Stack != "" {
+		r.AddAttrs(slog.String("stacktrace", ent.Stack))
+	}
+	return c.h.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error { return nil }
+
+//attrsOf 将zap的字段按顺序转换为slog.Attr,zap.Namespace之后的字段放入对应的group
+func attrsOf(fields []zapcore.Field) []slog.Attr {
+	var attrs []slog.Attr
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return append(attrs, slog.Attr{Key: f.Key, Value: slog.GroupValue(attrsOf(fields[i+1:])...)})
+		}
+		encodeField(f, func(k string, v any) {
+			attrs = append(attrs, slog.Any(k, v))
+		})
+	}
+	return attrs
+}
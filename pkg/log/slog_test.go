@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, logs := newObservedLogger()
+	SetVerbosity(1)
+	defer SetVerbosity(0)
+
+	sl := ToSlog(l.WithName("slog")).With("k", "v").WithGroup("g")
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	sl.InfoContext(ctx, "info message", "a", 1)
+	sl.Log(ctx, slog.LevelDebug-2, "v1 message")
+	sl.Log(ctx, slog.LevelDebug-3, "v2 message")
+	sl.Warn("warn message", slog.Group("sub", "b", true))
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	wantLevels := []zapcore.Level{InfoLevel, vLevel(1), WarnLevel}
+	for i, e := range entries {
+		if e.Level != wantLevels[i] {
+			t.Errorf("%s: level %v, want %v", e.Message, e.Level, wantLevels[i])
+		}
+		if e.LoggerName != "slog" || e.ContextMap()["k"] != "v" {
+			t.Errorf("%s: name %q, fields %v", e.Message, e.LoggerName, e.ContextMap())
+		}
+		if filepath.Base(e.Caller.File) != "slog_test.go" {
+			t.Errorf("%s: caller %s, want slog_test.go", e.Message, e.Caller.File)
+		}
+	}
+	fields := entries[0].ContextMap()
+	if fields[KeyRequestID] != "req-1" {
+		t.Errorf("context fields should be at the top level: %v", fields)
+	}
+	if g, _ := fields["g"].(map[string]any); g["a"] != int64(1) {
+		t.Errorf("attrs should be in group g: %v", fields)
+	}
+	g, _ := entries[2].ContextMap()["g"].(map[string]any)
+	if sub, _ := g["sub"].(map[string]any); sub["b"] != true {
+		t.Errorf("nested group: %v", entries[2].ContextMap())
+	}
+	//L(ctx)已经添加过的字段不重复添加,值变化时使用新的值
+	h := ToSlog(l.L(ctx))
+	h.InfoContext(ctx, "after L")
+	h.InfoContext(ContextWithRequestID(ctx, "req-2"), "nested request")
+	for i, want := range []string{"req-1", "req-2"} {
+		e := logs.All()[3+i]
+		n := 0
+		for _, f := range e.Context {
+			if f.Key == KeyRequestID {
+				n++
+			}
+		}
+		if i == 0 && n != 1 {
+			t.Errorf("%s: requestID added %d times: %v", e.Message, n, e.Context)
+		}
+		if got := e.ContextMap()[KeyRequestID]; got != want {
+			t.Errorf("%s: requestID = %v, want %s", e.Message, got, want)
+		}
+	}
+}
+
+func TestFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug - 4, AddSource: true})
+	SetVerbosity(1)
+	defer SetVerbosity(0)
+
+	l := FromSlog(h).WithName("db").WithValues("k", "v")
+	l.Info("info message", String("a", "b"))
+	l.V(1).Info("v1 message")
+	l.V(2).Info("v2 message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %s", len(lines), buf.String())
+	}
+	wantLevels := []string{"INFO", "DEBUG-2"}
+	for i, line := range lines {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["level"] != wantLevels[i] || m["logger"] != "db" || m["k"] != "v" {
+			t.Errorf("got %s", line)
+		}
+		source, _ := m["source"].(map[string]any)
+		if file, _ := source["file"].(string); filepath.Base(file) != "slog_test.go" {
+			t.Errorf("source should be the caller: %s", line)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"github.com/leilei3167/basic/pkg/errors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -8,6 +9,8 @@ import (
 const (
 	KeyRequestID string = "requestID"
 	KeyUsername  string = "username"
+	KeyTraceID   string = "traceID"
+	KeySpanID    string = "spanID"
 )
 
 //定义zap的field的一些别名
@@ -79,3 +82,90 @@ var (
 	Uintptr     = zap.Uintptr
 	Uintptrs    = zap.Uintptrs
 )
+
+// ErrorField 将错误以结构化的形式记录在error字段下,包括error.code,error.message,error.chain,
+// 通过New,Build创建的logger在写入该日志的logger和目的地都开启debug时还会记录error.stack;
+// log.Err只会记录Error()返回的字符串
+func ErrorField(err error) Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object("error", errorObject{err: err})
+}
+
+//errorObject 默认不记录堆栈,由errorCore在写入时根据级别决定是否替换为带堆栈的版本
+type errorObject struct {
+	err error
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return errors.LogObject(e.err, false).MarshalLogObject(enc)
+}
+
+//errorCore 包装每个目的地的core,logger和目的地都开启debug时,为ErrorField记录堆栈,
+//是否记录在Write时按照当时的级别判断,With的ErrorField同时保存不带和带堆栈的两个版本
+type errorCore struct {
+	zapcore.Core
+	stack  zapcore.Core //With过ErrorField时,其中的ErrorField记录堆栈,为nil时与Core相同
+	levels *nameLevels
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	if c.stack == nil && !hasErrorField(fields) {
+		return &errorCore{Core: c.Core.With(fields), levels: c.levels}
+	}
+	return &errorCore{Core: c.Core.With(fields), stack: c.stackCore().With(withErrorStack(fields, true)),
+		levels: c.levels}
+}
+
+func (c *errorCore) stackCore() zapcore.Core {
+	if c.stack == nil {
+		return c.Core
+	}
+	return c.stack
+}
+
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.levels.enabled(ent.LoggerName, zapcore.DebugLevel) && c.Core.Enabled(zapcore.DebugLevel) {
+		return c.stackCore().Write(ent, withErrorStack(fields, true))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+func hasErrorField(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if _, ok := f.Interface.(errorObject); ok && f.Type == zapcore.ObjectMarshalerType {
+			return true
+		}
+	}
+	return false
+}
+
+//withErrorStack stack为true时将ErrorField替换为记录堆栈的版本,不修改传入的fields
+func withErrorStack(fields []zapcore.Field, stack bool) []zapcore.Field {
+	if !stack {
+		return fields
+	}
+	var ret []zapcore.Field
+	for i, f := range fields {
+		e, ok := f.Interface.(errorObject)
+		if !ok || f.Type != zapcore.ObjectMarshalerType {
+			continue
+		}
+		if ret == nil {
+			ret = append([]zapcore.Field(nil), fields...)
+		}
+		ret[i] = zap.Object(f.Key, errors.LogObject(e.err, true))
+	}
+	if ret == nil {
+		return fields
+	}
+	return ret
+}
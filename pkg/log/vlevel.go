@@ -0,0 +1,160 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+//klog风格的整数日志级别,V(n)对应zap中低于Debug的级别 DebugLevel-1-n,
+//V(n)是否开启只由--log.v和--log.vmodule决定,开启后的日志按照Info级别输出到各个目的地
+
+const maxV = 100
+
+var (
+	verbosity atomic.Int32
+	vmodules  atomic.Pointer[vmodule]
+)
+
+//vLevel 返回V(n)对应的zap级别
+func vLevel(n int) zapcore.Level {
+	return zapcore.DebugLevel - 1 - zapcore.Level(n)
+}
+
+//enabledLevel 将V级别当做Info级别,用于各个core判断是否开启
+func enabledLevel(lvl zapcore.Level) zapcore.Level {
+	if lvl < zapcore.DebugLevel {
+		return zapcore.InfoLevel
+	}
+	return lvl
+}
+
+//sinkLevel 目的地的级别,V级别按照Info判断
+type sinkLevel zapcore.Level
+
+func (s sinkLevel) Enabled(lvl zapcore.Level) bool {
+	return enabledLevel(lvl) >= zapcore.Level(s)
+}
+
+//vLevelEncoder 将V级别输出为V2这样的形式,其他级别使用enc
+func vLevelEncoder(enc zapcore.LevelEncoder) zapcore.LevelEncoder {
+	return func(lvl zapcore.Level, pae zapcore.PrimitiveArrayEncoder) {
+		if lvl < zapcore.DebugLevel {
+			pae.AppendString("V" + strconv.Itoa(int(zapcore.DebugLevel-1-lvl)))
+			return
+		}
+		enc(lvl, pae)
+	}
+}
+
+// SetVerbosity 修改V(n)的全局开关,V(n)在n<=v时开启
+func SetVerbosity(v int) {
+	verbosity.Store(int32(clampV(v)))
+}
+
+// Verbosity 返回当前的全局V级别
+func Verbosity() int {
+	return int(verbosity.Load())
+}
+
+func clampV(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > maxV {
+		return maxV
+	}
+	return v
+}
+
+type vmodule struct {
+	patterns []vpattern
+	cache    sync.Map //pc -> int32,没有匹配的pattern时为-1
+}
+
+type vpattern struct {
+	pattern string
+	full    bool //包含"/"时匹配完整路径,否则只匹配文件名
+	v       int32
+}
+
+// SetVModule 按文件设置V级别,格式与klog一致,如 "server=2,pkg/db/*=4",
+// pattern是不带.go后缀的文件名或路径,支持filepath.Match的通配符,为空时清除
+func SetVModule(spec string) error {
+	m, err := parseVModule(spec)
+	if err != nil {
+		return err
+	}
+	vmodules.Store(m)
+	return nil
+}
+
+func parseVModule(spec string) (*vmodule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	m := &vmodule{}
+	for _, part := range strings.Split(spec, ",") {
+		pattern, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid vmodule %q: expect pattern=N", part)
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid vmodule %q: level must be a non-negative integer", part)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vmodule %q: %v", part, err)
+		}
+		pattern = strings.TrimSuffix(pattern, ".go")
+		m.patterns = append(m.patterns, vpattern{pattern: pattern, full: strings.Contains(pattern, "/"), v: int32(clampV(v))})
+	}
+	return m, nil
+}
+
+//verbosityAt 返回pc所在文件的V级别,结果按pc缓存
+func (m *vmodule) verbosityAt(pc uintptr) int32 {
+	if v, ok := m.cache.Load(pc); ok {
+		return v.(int32)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	file := strings.TrimSuffix(frame.File, ".go")
+
+	v := int32(-1)
+	for _, p := range m.patterns {
+		name := filepath.Base(file)
+		if p.full {
+			name = file
+		}
+		if ok, _ := filepath.Match(p.pattern, name); ok || (p.full && strings.HasSuffix(file, "/"+p.pattern)) {
+			v = p.v
+			break
+		}
+	}
+	m.cache.Store(pc, v)
+	return v
+}
+
+//vEnabled 判断V(n)是否开启,没有设置vmodule时只是一次原子读取;
+//skip为调用vEnabled的函数到用户代码之间的层数
+func vEnabled(n int, skip int) bool {
+	m := vmodules.Load()
+	if m == nil {
+		return int32(n) <= verbosity.Load()
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return int32(n) <= verbosity.Load()
+	}
+	if v := m.verbosityAt(pcs[0]); v >= 0 {
+		return int32(n) <= v
+	}
+	return int32(n) <= verbosity.Load()
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestV(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "v.json")
+	opts := NewOptions()
+	opts.Format = jsonFormat
+	opts.OutputPaths = []string{filename}
+	opts.V = 1
+	prev := std
+	defer setStd(prev)
+	l, err := opts.Build(InstallAsStd(), NoGlobals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetVerbosity(0)
+
+	//没有InstallAsStd时不修改全局的V级别
+	other := NewOptions()
+	other.OutputPaths = []string{filename}
+	if _, err := other.Build(NoGlobals()); err != nil {
+		t.Fatal(err)
+	}
+	if Verbosity() != 1 {
+		t.Fatalf("a second Build should not clobber the verbosity, got %d", Verbosity())
+	}
+
+	if !l.V(1).Enabled() || l.V(2).Enabled() {
+		t.Errorf("V(1) should be enabled and V(2) disabled with v=1")
+	}
+	l.V(1).Info("v1 message")
+	l.V(2).Info("v2 message")
+
+	if err := SetVModule("other=5,vlevel_test=3"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetVModule("")
+	if !l.V(3).Enabled() || l.V(4).Enabled() || !CheckIntLevel(3) {
+		t.Errorf("vmodule should enable V(3) in this file only")
+	}
+	l.V(3).Infow("v3 message", "key", "value")
+	if err := SetVModule("log/vlevel_test=0"); err != nil {
+		t.Fatal(err)
+	}
+	if l.V(1).Enabled() {
+		t.Error("vmodule with path should override the global verbosity")
+	}
+	l.Flush()
+
+	data, _ := os.ReadFile(filename)
+	out := string(data)
+	if !strings.Contains(out, `"level":"V1","timestamp"`) || !strings.Contains(out, `"level":"V3"`) || strings.Contains(out, "v2 message") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	SetLevel(WarnLevel)
+	defer SetLevel(InfoLevel)
+	if V(0).Enabled() {
+		t.Error("V logs are info logs, they should be disabled at warn level")
+	}
+
+	if _, err := parseVModule("server"); err == nil {
+		t.Error("want error for vmodule without level")
+	}
+}